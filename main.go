@@ -3,13 +3,12 @@ package main
 import (
 	"context"
 	"os/signal"
-	"syscall"
 
 	"github.com/bdoerfchen/webcmd/src/cmd"
 )
 
 func main() {
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	ctx, cancel := signal.NotifyContext(context.Background(), cmd.ShutdownSignals...) // SIGHUP reloads the configuration and certificates
 	defer cancel()
 
 	cmd.Start(ctx)
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/cacher"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/health"
+	"github.com/bdoerfchen/webcmd/src/common/metrics"
+	"github.com/bdoerfchen/webcmd/src/common/router"
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
+	"github.com/bdoerfchen/webcmd/src/logging"
+	"github.com/bdoerfchen/webcmd/src/services/configloader"
+)
+
+// Interval in which the config file is checked for changes
+const watchInterval = 2 * time.Second
+
+// Serves requests with the current router. Requests in flight finish on the router they were started with
+type routerSwitch struct {
+	current atomic.Pointer[switchedRouter]
+}
+
+type switchedRouter struct {
+	router  router.Router
+	handler http.Handler
+	admin   http.Handler // Handler of the admin API, nil if it is disabled
+}
+
+// Serve all following requests with the router
+func (s *routerSwitch) Set(router router.Router, adminConfig *config.AdminConfig, logger *slog.Logger) error {
+	next := &switchedRouter{router: router, handler: router.Handler()}
+	if adminConfig != nil {
+		admin, err := router.AdminHandler(adminConfig.Auth, logger.With(slog.String("logger", "admin")))
+		if err != nil {
+			return fmt.Errorf("failed to create admin API: %w", err)
+		}
+		next.admin = admin
+	}
+
+	previous := s.current.Swap(next)
+	if previous != nil {
+		// Requests in flight on the previous router may still finish
+		previous.router.Close()
+	}
+	return nil
+}
+
+func (s *routerSwitch) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.current.Load().handler.ServeHTTP(w, req)
+}
+
+// Handler of the admin API of the current router
+func (s *routerSwitch) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if admin := s.current.Load().admin; admin != nil {
+			admin.ServeHTTP(w, req)
+			return
+		}
+		http.NotFound(w, req)
+	})
+}
+
+// Reloads the configuration and swaps the router. The running configuration is kept if the new one has critical remarks
+type reloader struct {
+	mutex       sync.Mutex
+	config      *config.AppConfig // Running configuration
+	routers     *routerSwitch
+	routeChecks *routeHealthChecks
+	executers   *execution.ExecuterCollection
+	shellPool   shellPool
+	cacher      cacher.Cacher
+	recorder    metrics.Recorder
+	tracer      tracing.Tracer
+	monitor     health.Monitor
+	server      interface{ ReloadTLS() error }
+	logger      *slog.Logger
+}
+
+// Reload whenever a SIGHUP signal is received and, if watching, when the config file changes
+func (r *reloader) Run(ctx context.Context, watch bool) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var changed <-chan time.Time
+	var lastModified time.Time
+	path := configloader.FilePath(flagConfigFilePath)
+	if watch {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		changed = ticker.C
+		if info, err := os.Stat(path); err == nil {
+			lastModified = info.ModTime()
+		}
+		r.logger.Debug("watching config file", slog.String("path", path))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			r.logger.Info("received SIGHUP, reloading certificates and configuration")
+			if err := r.server.ReloadTLS(); err != nil {
+				r.logger.Error("failed to reload certificates, keeping the previous ones", slog.String("error", err.Error()))
+			}
+			r.Reload(ctx)
+		case <-changed:
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Equal(lastModified) {
+				continue
+			}
+			lastModified = info.ModTime()
+			r.logger.Info("config file changed, reloading configuration", slog.String("path", path))
+			r.Reload(ctx)
+		}
+	}
+}
+
+// Load and check the configuration, and serve following requests with a router for it
+func (r *reloader) Reload(ctx context.Context) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	runCtx := ctx
+	ctx, cancel := context.WithTimeout(logging.AddToContext(ctx, r.logger), 30*time.Second)
+	defer cancel()
+
+	next, err := readConfig(ctx, r.logger)
+	if err != nil {
+		r.logger.Error("failed to reload configuration, keeping the running one", slog.String("error", err.Error()))
+		return
+	}
+	for _, section := range keepRestartSettings(r.config, next) {
+		r.logger.Warn(fmt.Sprintf("changes of %s require a restart and are not applied", section))
+	}
+
+	router, err := setupRouter(ctx, next, r.executers, r.cacher, r.recorder, r.tracer, r.monitor, r.logger)
+	if err == nil {
+		if current := r.routers.current.Load(); current != nil {
+			router.Inherit(current.router)
+		}
+		err = r.routers.Set(router, next.Modules.Admin, r.logger)
+	}
+	if err != nil {
+		if router != nil {
+			router.Close()
+		}
+		r.logger.Error("failed to reload configuration, keeping the running one", slog.String("error", err.Error()))
+		return
+	}
+
+	if size := next.Modules.ShellPool.Size; size != r.config.Modules.ShellPool.Size {
+		r.shellPool.Resize(size)
+		r.logger.Info(fmt.Sprintf("shell pool resized from %v to %v", r.config.Modules.ShellPool.Size, size))
+	}
+	r.routeChecks.Set(runCtx, next)
+	r.config = next
+	r.logger.Info("configuration reloaded", slog.Int("routes", len(next.Routes)), slog.Int("groups", len(next.Groups)))
+}
+
+// Reset the settings of the next config that can only be applied on start to the running ones. Returns the names of the changed settings
+func keepRestartSettings(running, next *config.AppConfig) (changed []string) {
+	keep(&changed, "server", running.Server, &next.Server)
+	keep(&changed, "shell pool path", running.Modules.ShellPool.Path, &next.Modules.ShellPool.Path)
+	keep(&changed, "shell pool args", running.Modules.ShellPool.Args, &next.Modules.ShellPool.Args)
+	keep(&changed, "cache", running.Modules.Cache, &next.Modules.Cache)
+	keep(&changed, "metrics", running.Modules.Metrics, &next.Modules.Metrics)
+	keep(&changed, "tracing", running.Modules.Tracing, &next.Modules.Tracing)
+	keep(&changed, "health", running.Modules.Health, &next.Modules.Health)
+	keep(&changed, "admin", running.Modules.Admin, &next.Modules.Admin)
+	return
+}
+
+func keep[T any](changed *[]string, name string, running T, next *T) {
+	if !reflect.DeepEqual(running, *next) {
+		*changed = append(*changed, name)
+		*next = running
+	}
+}
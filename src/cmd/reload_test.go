@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/metrics"
+	"github.com/bdoerfchen/webcmd/src/common/process"
+	"github.com/bdoerfchen/webcmd/src/common/router"
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
+	"github.com/bdoerfchen/webcmd/src/services/healthmonitor"
+	"github.com/bdoerfchen/webcmd/src/services/springercacher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A router serving all requests with its handler
+type fakeRouter struct {
+	router.Router
+	handler http.Handler
+	closed  atomic.Bool
+}
+
+func (r *fakeRouter) Handler() http.Handler {
+	return r.handler
+}
+
+func (r *fakeRouter) Close() {
+	r.closed.Store(true)
+}
+
+func TestRouterSwitch(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	previous := &fakeRouter{handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("previous"))
+	})}
+	next := &fakeRouter{handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("next"))
+	})}
+	logger := slog.New(slog.DiscardHandler)
+
+	var routers routerSwitch
+	require.NoError(t, routers.Set(previous, nil, logger))
+	inFlight := make(chan string)
+	go func() {
+		recorder := httptest.NewRecorder()
+		routers.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		inFlight <- recorder.Body.String()
+	}()
+	<-started
+
+	// Following requests are served by the next router, while the request in flight finishes on the previous one
+	require.NoError(t, routers.Set(next, nil, logger))
+	assert.True(t, previous.closed.Load())
+	recorder := httptest.NewRecorder()
+	routers.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "next", recorder.Body.String())
+	close(release)
+	assert.Equal(t, "previous", <-inFlight)
+
+	// The admin API is not found without admin config
+	recorder = httptest.NewRecorder()
+	routers.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/routes", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+// An executer counting its executions
+type countingExecuter struct {
+	count atomic.Int32
+}
+
+func (e *countingExecuter) Execute(ctx context.Context, config execution.Config) (*process.Process, int, error) {
+	proc := &process.Process{}
+	stdout, _ := proc.Writers()
+	fmt.Fprint(stdout, e.count.Add(1))
+	return proc, 0, nil
+}
+
+func (e *countingExecuter) Describe() (execution.ExecMode, []any) {
+	return execution.ModeProc, []any{}
+}
+
+// A shell pool recording its size
+type fakeShellPool struct {
+	size uint
+}
+
+func (p *fakeShellPool) Resize(size uint) {
+	p.size = size
+}
+
+func (p *fakeShellPool) Close(timeout time.Duration) {}
+
+type fakeTLSServer struct{}
+
+func (s *fakeTLSServer) ReloadTLS() error {
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.config.yaml")
+	previousPath := flagConfigFilePath
+	flagConfigFilePath = path
+	defer func() { flagConfigFilePath = previousPath }()
+
+	writeConfig := func(port, poolSize int, message, countHeader string, broken bool) {
+		content := fmt.Sprintf(`server:
+  port: %v
+modules:
+  shellPool:
+    size: %v
+routes:
+- route: "/message"
+  static:
+    body: %q
+- route: "/count"
+  caching: true
+  headers:
+    X-Count: %q
+  exec:
+    proc:
+      path: "test"
+`, port, poolSize, message, countHeader)
+		if broken {
+			content += "- route: \"/broken\"\n  redirect:\n    statusCode: 200\n    location: \"/\"\n"
+		}
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	writeConfig(8080, 2, "first", "a", false)
+
+	// Setup as on start
+	ctx := context.Background()
+	logger := slog.New(slog.DiscardHandler)
+	running, err := readConfig(ctx, logger)
+	require.NoError(t, err)
+	var executers execution.ExecuterCollection
+	executers.Add(&countingExecuter{})
+	cacher, err := springercacher.New(&running.Modules.Cache)
+	require.NoError(t, err)
+	monitor := healthmonitor.New(logger)
+	initial, err := setupRouter(ctx, running, &executers, cacher, metrics.Discard, tracing.Discard, monitor, logger)
+	require.NoError(t, err)
+	routers := &routerSwitch{}
+	require.NoError(t, routers.Set(initial, nil, logger))
+	pool := &fakeShellPool{size: running.Modules.ShellPool.Size}
+	reloader := &reloader{
+		config:      running,
+		routers:     routers,
+		routeChecks: &routeHealthChecks{monitor: monitor},
+		executers:   &executers,
+		shellPool:   pool,
+		cacher:      cacher,
+		recorder:    metrics.Discard,
+		tracer:      tracing.Discard,
+		monitor:     monitor,
+		server:      &fakeTLSServer{},
+		logger:      logger,
+	}
+
+	get := func(path string) string {
+		recorder := httptest.NewRecorder()
+		routers.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Body.String()
+	}
+	assert.Equal(t, "first", get("/message"))
+	assert.Equal(t, "1", get("/count"))
+
+	testCases := []struct {
+		Name             string
+		Port             int
+		PoolSize         int
+		Message          string
+		CountHeader      string
+		Broken           bool
+		ExpectedMessage  string
+		ExpectedCount    string
+		ExpectedPoolSize uint
+	}{
+		{Name: "routes replaced", Port: 8080, PoolSize: 2, Message: "second", CountHeader: "a", ExpectedMessage: "second", ExpectedCount: "1", ExpectedPoolSize: 2},
+		{Name: "shell pool resized", Port: 8080, PoolSize: 4, Message: "second", CountHeader: "a", ExpectedMessage: "second", ExpectedCount: "1", ExpectedPoolSize: 4},
+		{Name: "server kept", Port: 9090, PoolSize: 4, Message: "third", CountHeader: "a", ExpectedMessage: "third", ExpectedCount: "1", ExpectedPoolSize: 4},
+		{Name: "critical remarks keep running config", Port: 8080, PoolSize: 1, Message: "broken", CountHeader: "b", Broken: true, ExpectedMessage: "third", ExpectedCount: "1", ExpectedPoolSize: 4},
+		{Name: "cache of changed route purged", Port: 8080, PoolSize: 4, Message: "third", CountHeader: "b", ExpectedMessage: "third", ExpectedCount: "2", ExpectedPoolSize: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			writeConfig(tc.Port, tc.PoolSize, tc.Message, tc.CountHeader, tc.Broken)
+			reloader.Reload(ctx)
+
+			assert.Equal(t, tc.ExpectedMessage, get("/message"))
+			assert.Equal(t, tc.ExpectedCount, get("/count"))
+			assert.Equal(t, tc.ExpectedPoolSize, pool.size)
+			assert.Equal(t, tc.ExpectedPoolSize, reloader.config.Modules.ShellPool.Size)
+			assert.Equal(t, uint16(8080), reloader.config.Server.Port)
+		})
+	}
+}
@@ -2,23 +2,49 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/cacher"
 	"github.com/bdoerfchen/webcmd/src/common/config"
 	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/health"
+	"github.com/bdoerfchen/webcmd/src/common/metrics"
 	"github.com/bdoerfchen/webcmd/src/common/process"
 	"github.com/bdoerfchen/webcmd/src/common/router"
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
 	"github.com/bdoerfchen/webcmd/src/common/version"
 	"github.com/bdoerfchen/webcmd/src/logging"
+	"github.com/bdoerfchen/webcmd/src/services/basicauth"
+	"github.com/bdoerfchen/webcmd/src/services/certauth"
 	"github.com/bdoerfchen/webcmd/src/services/chirouter"
 	"github.com/bdoerfchen/webcmd/src/services/configloader"
+	"github.com/bdoerfchen/webcmd/src/services/fcgiexecuter"
+	"github.com/bdoerfchen/webcmd/src/services/healthmonitor"
+	"github.com/bdoerfchen/webcmd/src/services/jwtauth"
+	"github.com/bdoerfchen/webcmd/src/services/openapidoc"
+	"github.com/bdoerfchen/webcmd/src/services/otlptracer"
+	"github.com/bdoerfchen/webcmd/src/services/pipeexecuter"
 	"github.com/bdoerfchen/webcmd/src/services/procexecuter"
+	"github.com/bdoerfchen/webcmd/src/services/promexporter"
 	"github.com/bdoerfchen/webcmd/src/services/server"
 	"github.com/bdoerfchen/webcmd/src/services/shellexecuter"
 	"github.com/bdoerfchen/webcmd/src/services/springercacher"
+	"github.com/bdoerfchen/webcmd/src/services/sshexecuter"
+	"github.com/bdoerfchen/webcmd/src/services/tokenauth"
 	"github.com/spf13/cobra"
 )
 
@@ -40,8 +66,21 @@ var runCmd = &cobra.Command{
 	},
 }
 
+var openapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Print the OpenAPI document of the configured routes",
+	Example: `
+  webcmd openapi -c webcmd.conf
+  webcmd openapi -c webcmd.conf -o openapi.json
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		openapiExec(cmd.Context())
+	},
+}
+
 var flagVerbose bool
 var flagDryRun bool
+var flagWatch bool
 var flagNoColor bool
 
 var flagServerHost string
@@ -49,6 +88,7 @@ var flagServerPort uint16
 var flagRoutePattern string
 var flagRouteMethod string
 var flagConfigFilePath string
+var flagOutputFile string
 
 func Start(ctx context.Context) {
 	// Persistent flags
@@ -58,6 +98,7 @@ func Start(ctx context.Context) {
 	// Config file path flag
 	runCmd.Flags().StringVarP(&flagConfigFilePath, "config-file", "c", "", "Path to the webcmd config file")
 	runCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "Define whether server start should be skipped")
+	runCmd.Flags().BoolVar(&flagWatch, "watch", false, "Reload the configuration when the config file changes")
 	// Convenient flags for easy route configuration
 	runCmd.Flags().StringVarP(&flagRouteMethod, "method", "m", "", "Set the HTTP method for the default route")
 	runCmd.Flags().StringVarP(&flagRoutePattern, "route", "r", "", "Set the pattern for the default route")
@@ -65,6 +106,10 @@ func Start(ctx context.Context) {
 	runCmd.Flags().Uint16VarP(&flagServerPort, "port", "p", 0, "Set the server port")
 	runCmd.Flags().StringVar(&flagServerHost, "host", "", "Set the host IP address to listen to")
 
+	// OpenAPI command flags
+	openapiCmd.Flags().StringVarP(&flagConfigFilePath, "config-file", "c", "", "Path to the webcmd config file")
+	openapiCmd.Flags().StringVarP(&flagOutputFile, "output", "o", "", "Write the document to a file instead of stdout")
+
 	// Configure version command
 	rootCmd.Version = version.Full()
 	if sha, err := version.CommitSha(); err == nil {
@@ -72,9 +117,19 @@ func Start(ctx context.Context) {
 	}
 
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(openapiCmd)
 	rootCmd.ExecuteContext(ctx)
 }
 
+// Signals shutting the server down. Another one during the drain kills the running executions and exits immediately
+var ShutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+
+// Time executions and pooled shells get to exit on shutdown before they are killed
+const terminateGrace = 5 * time.Second
+
+// Read timeout of the metrics and admin servers, which receive no large requests
+const internalReadTimeout = timem.Duration(5 * time.Second)
+
 func runExec(ctx context.Context) {
 	// Configure logger and setup context
 	logLevel := slog.LevelInfo
@@ -90,8 +145,21 @@ func runExec(ctx context.Context) {
 
 	// Load config
 	config := loadConfig(setupCtx, logger)
-	// Load router
-	router := setupRouter(setupCtx, config, logger)
+	// Load metrics, tracing, executers and router
+	recorder := setupMetrics(config, logger)
+	tracer, flushSpans := setupTracing(config, logger)
+	monitor, routeChecks := setupHealth(ctx, config, logger)
+	executers, shellPool, closeConnections := setupExecuters(config, recorder, monitor, logger)
+	cacher := setupCache(config, logger)
+	router, err := setupRouter(setupCtx, config, executers, cacher, recorder, tracer, monitor, logger)
+	routers := &routerSwitch{}
+	if err == nil {
+		err = routers.Set(router, config.Modules.Admin, logger)
+	}
+	if err != nil {
+		logger.Error(err.Error())
+		shutdown(logger, false)
+	}
 	finishSetup() // Cancel setupCtx
 	logger.Debug("setup finished")
 	fmt.Println() // Empty log line
@@ -103,13 +171,115 @@ func runExec(ctx context.Context) {
 	}
 
 	runCtx := logging.AddToContext(ctx, logger)
-	server := server.New(config.Server)
-	err := server.Run(runCtx, router.Handler())
+	mainServer, err := server.New(config.Server)
 	if err != nil {
+		logger.Error("failed to create server", slog.String("error", err.Error()))
+		shutdown(logger, false)
+	}
+	reloader := &reloader{
+		config:      config,
+		routers:     routers,
+		routeChecks: routeChecks,
+		executers:   executers,
+		shellPool:   shellPool,
+		cacher:      cacher,
+		recorder:    recorder,
+		tracer:      tracer,
+		monitor:     monitor,
+		server:      mainServer,
+		logger:      logger,
+	}
+	go reloader.Run(runCtx, flagWatch)
+	if config.Modules.Metrics != nil && config.Modules.Metrics.Separate() {
+		go runMetricsServer(runCtx, config.Modules.Metrics, recorder, logger)
+	}
+	if config.Modules.Admin != nil {
+		go runAdminServer(runCtx, config.Modules.Admin, routers.AdminHandler(), logger)
+	}
+	monitor.AddCheck("shutdown", func() error {
+		if ctx.Err() != nil {
+			return errors.New("shutting down")
+		}
+		return nil
+	})
+	context.AfterFunc(ctx, func() {
+		// The signal of the shutdown is consumed by the context, so this waits for another one
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, ShutdownSignals...)
+		<-signals
+		logger.Warn("received another signal during the drain, killing the running executions")
+		executers.Running().Terminate(0)
+		shellPool.Close(0)
+		closeConnections()
+		shutdown(logger, false)
+	})
+	err = mainServer.Run(runCtx, routers)
+	clean := err == nil
+	if errors.Is(err, server.ErrDrainTimeout) {
+		// Terminate the process groups of the executions still running
+		logger.Warn(fmt.Sprintf("%v executions still running after the drain timeout, terminating them", executers.Running().Count()))
+		if killed := executers.Running().Terminate(terminateGrace); killed > 0 {
+			logger.Warn(fmt.Sprintf("killed %v executions that did not terminate", killed))
+		}
+	} else if err != nil {
 		logger.Error(err.Error())
 	}
+	shellPool.Close(terminateGrace)
+	closeConnections()
+	flushSpans()
+
+	shutdown(logger, clean)
+}
+
+func openapiExec(ctx context.Context) {
+	// Only errors are logged, as the document is written to stdout
+	logLevel := slog.LevelError
+	if flagVerbose {
+		logLevel = slog.LevelDebug
+	}
+	logger := logging.New(logLevel, !flagNoColor)
+	ctx = logging.AddToContext(ctx, logger)
+
+	config := loadConfig(ctx, logger)
+	document, err := json.MarshalIndent(openapidoc.Generate(config.Routes, config.Groups, &config.Modules), "", "  ")
+	if err != nil {
+		logger.Error("failed to generate the OpenAPI document", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	document = append(document, '\n')
+
+	if flagOutputFile == "" {
+		os.Stdout.Write(document)
+		return
+	}
+	if err := os.WriteFile(flagOutputFile, document, 0o644); err != nil {
+		logger.Error("failed to write the OpenAPI document", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+// Serve the metrics on their own listener
+func runMetricsServer(ctx context.Context, metricsConfig *config.MetricsConfig, recorder metrics.Recorder, logger *slog.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("GET "+metricsConfig.Path, recorder.Handler())
+	metricsServer, err := server.New(server.Config{Host: metricsConfig.Host, Port: metricsConfig.Port, ReadTimeout: internalReadTimeout})
+	if err == nil {
+		err = metricsServer.Run(ctx, mux)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("metrics server failed", slog.String("error", err.Error()))
+	}
+}
 
-	shutdown(logger, true)
+// Serve the admin API on its own listener
+func runAdminServer(ctx context.Context, adminConfig *config.AdminConfig, handler http.Handler, logger *slog.Logger) {
+	adminServer, err := server.New(server.Config{Host: adminConfig.Host, Port: adminConfig.Port, Socket: adminConfig.Socket, ReadTimeout: internalReadTimeout})
+	if err == nil {
+		err = adminServer.Run(ctx, handler)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("admin server failed", slog.String("error", err.Error()))
+	}
 }
 
 func shutdown(logger *slog.Logger, ok bool) {
@@ -121,17 +291,26 @@ func shutdown(logger *slog.Logger, ok bool) {
 	os.Exit(0)
 }
 
-// Load application config from config file or/and command flags
+// Load application config from config file or/and command flags. Exits on errors
 func loadConfig(ctx context.Context, logger *slog.Logger) *config.AppConfig {
+	config, err := readConfig(ctx, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		shutdown(logger, false)
+	}
+
+	return config
+}
+
+// Read and check the application config from config file or/and command flags
+func readConfig(ctx context.Context, logger *slog.Logger) (*config.AppConfig, error) {
 
 	// Read config
 	logger.Debug("load server configuration")
 	loader := configloader.New()
 	config, err := loader.Load(ctx, flagConfigFilePath)
 	if err != nil {
-		logger.Error("failed to load config file: " + err.Error())
-		shutdown(logger, false)
-		return nil
+		return nil, fmt.Errorf("failed to load config file: %w", err)
 	}
 	// Merge parameters from cmd flags into app config
 	mergeCommandFlags(config, logger)
@@ -139,48 +318,196 @@ func loadConfig(ctx context.Context, logger *slog.Logger) *config.AppConfig {
 
 	// Check configuration
 	if err = checkConfig(config, logger); err != nil {
-		logger.Error(err.Error())
-		shutdown(logger, false)
+		return nil, err
 	}
 
-	return config
+	return config, nil
 }
 
-// Router integration with given app config
-func setupRouter(ctx context.Context, config *config.AppConfig, logger *slog.Logger) router.Router {
-	// Setup cache
-	cacher, err := springercacher.New(&config.Modules.Cache)
-	if err != nil {
-		logger.Error("failed to create cache module", slog.String("error", err.Error()))
-		shutdown(logger, false)
+// Metrics recorder, if metrics are enabled
+func setupMetrics(config *config.AppConfig, logger *slog.Logger) metrics.Recorder {
+	if config.Modules.Metrics == nil {
+		return metrics.Discard
 	}
 
-	// Setup executers (proc + shell)
-	var executers execution.ExecuterCollection
-	executers.Add(procexecuter.New())      // Normal proc executer
-	executers.SetExcept(shellexecuter.New( // Shell executer with pool, except for windows
+	logger.Debug("metrics enabled", slog.String("path", config.Modules.Metrics.Path))
+	return promexporter.New()
+}
+
+// Tracer exporting spans, if tracing is enabled. The returned function exports the remaining spans on shutdown
+func setupTracing(config *config.AppConfig, logger *slog.Logger) (tracing.Tracer, func()) {
+	if config.Modules.Tracing == nil {
+		return tracing.Discard, func() {}
+	}
+
+	logger.Debug("tracing enabled", slog.String("endpoint", config.Modules.Tracing.TracesURL()))
+	tracer := otlptracer.New(config.Modules.Tracing, logger)
+	return tracer, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracer.Shutdown(ctx); err != nil {
+			logger.Warn("failed to export remaining spans", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// Health monitor running the health check commands of the server and routes until the context is done.
+// The returned route checks are replaced on reload
+func setupHealth(ctx context.Context, appConfig *config.AppConfig, logger *slog.Logger) (health.Monitor, *routeHealthChecks) {
+	monitor := healthmonitor.New(logger)
+	for i := range appConfig.Modules.Health.Checks {
+		check := &appConfig.Modules.Health.Checks[i]
+		monitor.AddCommand(ctx, check.Name, check)
+	}
+
+	routeChecks := &routeHealthChecks{monitor: monitor}
+	routeChecks.Set(ctx, appConfig)
+	return monitor, routeChecks
+}
+
+// The health check commands of the routes
+type routeHealthChecks struct {
+	monitor interface {
+		health.Monitor
+		AddCommand(ctx context.Context, name string, healthCheck *config.HealthCheck)
+	}
+	names  []string
+	cancel context.CancelFunc // Stops the running commands
+}
+
+// Stop the checks of the previous routes and run those of the config until the context is done
+func (c *routeHealthChecks) Set(ctx context.Context, appConfig *config.AppConfig) {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	for _, name := range c.names {
+		c.monitor.RemoveCheck(name)
+	}
+	c.names = nil
+	ctx, c.cancel = context.WithCancel(ctx)
+
+	addRouteChecks := func(prefix string, routes []config.Route) {
+		for _, route := range routes {
+			if route.HealthCheck != nil {
+				name := route.Method + " " + prefix + route.Route
+				c.monitor.AddCommand(ctx, name, route.HealthCheck)
+				c.names = append(c.names, name)
+			}
+		}
+	}
+	addRouteChecks("", appConfig.Routes)
+	for _, group := range appConfig.Groups {
+		addRouteChecks(group.Prefix, group.Routes)
+	}
+}
+
+// Shell pool of the shell executer, controlled on reload and shutdown
+type shellPool interface {
+	Resize(size uint)
+	Close(timeout time.Duration)
+}
+
+// Executers of all exec modes, and the shell pool. The returned function closes the pooled ssh connections on shutdown
+func setupExecuters(config *config.AppConfig, recorder metrics.Recorder, monitor health.Monitor, logger *slog.Logger) (*execution.ExecuterCollection, shellPool, func()) {
+	// Setup executers (proc + pipeline + shell + fastcgi + ssh)
+	shellExecuter := shellexecuter.New(
 		config.Modules.ShellPool.Size,
 		process.Template{
 			Command:   config.Modules.ShellPool.Path,
 			Args:      config.Modules.ShellPool.Args,
 			OpenStdIn: true,
 		},
-	), "windows")
+	)
+	sshExecuter := sshexecuter.New()
+	var executers execution.ExecuterCollection
+	executers.Add(procexecuter.New())             // Normal proc executer
+	executers.Add(pipeexecuter.New())             // Chained proc executer
+	executers.Add(fcgiexecuter.New())             // FastCGI client executer
+	executers.Add(sshExecuter)                    // Remote executer over ssh
+	executers.SetExcept(shellExecuter, "windows") // Shell executer with pool, except for windows
+	recorder.AddGauge("webcmd_shell_pool_available", "Number of prepared shell processes", func() float64 { return float64(shellExecuter.Available()) })
+	recorder.AddGauge("webcmd_shell_pool_capacity", "Maximum number of prepared shell processes", func() float64 { return float64(shellExecuter.Capacity()) })
+	if runtime.GOOS != "windows" {
+		// Ready once the pool was filled, later it may be empty under load
+		var filled atomic.Bool
+		monitor.AddCheck("shell pool", func() error {
+			if filled.Load() || shellExecuter.Available() == shellExecuter.Capacity() {
+				filled.Store(true)
+				return nil
+			}
+			return fmt.Errorf("%v of %v shell processes prepared", shellExecuter.Available(), shellExecuter.Capacity())
+		})
+	}
 
-	// Setup routers with executers
-	var router router.Router = chirouter.New(&executers, cacher)
-	logger.Debug("router initialized:")
+	logger.Debug("executers initialized:")
 	for _, executer := range executers.Available() {
 		mode, attributes := executer.Describe()
 		logger.Debug(fmt.Sprintf("- enabled %s executer", string(mode)), attributes...)
 	}
 
-	// Register routes
-	err = router.Register(ctx, config.Routes)
+	return &executers, shellExecuter, sshExecuter.Close
+}
+
+// Response cache shared by the routers of all reloads
+func setupCache(config *config.AppConfig, logger *slog.Logger) cacher.Cacher {
+	cacher, err := springercacher.New(&config.Modules.Cache)
 	if err != nil {
-		logger.Error("error during route registration: " + err.Error())
+		logger.Error("failed to create cache module", slog.String("error", err.Error()))
 		shutdown(logger, false)
 	}
+	return cacher
+}
+
+// Router integration with given app config
+func setupRouter(ctx context.Context, config *config.AppConfig, executers *execution.ExecuterCollection, cacher cacher.Cacher, recorder metrics.Recorder, tracer tracing.Tracer, monitor health.Monitor, logger *slog.Logger) (router.Router, error) {
+	// Setup authenticators
+	authenticators := make(auth.Collection)
+	for name, authConfig := range config.Modules.Auth {
+		authenticator, err := newAuthenticator(&authConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create authenticator %s: %w", name, err)
+		}
+		authenticators[name] = authenticator
+	}
+
+	// Setup routers with executers
+	var router router.Router = chirouter.New(chirouter.Options{
+		Executers:      executers,
+		Modules:        &config.Modules,
+		Cacher:         cacher,
+		Authenticators: authenticators,
+		Recorder:       recorder,
+		Tracer:         tracer,
+		Monitor:        monitor,
+	})
+	logger.Debug("router initialized:")
+	for _, name := range slices.Sorted(maps.Keys(authenticators)) {
+		authConfig := config.Modules.Auth[name]
+		logger.Debug(fmt.Sprintf("- enabled %s authenticator %s", authConfig.Types()[0], name))
+	}
+
+	// Register routes
+	err := router.Register(ctx, config.Routes, config.Groups)
+	if err != nil {
+		return nil, fmt.Errorf("error during route registration: %w", err)
+	}
+
+	return router, nil
+}
+
+// Create the authenticator of the first configured type
+func newAuthenticator(authConfig *config.AuthenticatorConfig) (auth.Authenticator, error) {
+	switch {
+	case authConfig.Basic != nil:
+		return basicauth.New(authConfig.Basic)
+	case authConfig.Bearer != nil:
+		return tokenauth.New(authConfig.Bearer)
+	case authConfig.JWT != nil:
+		return jwtauth.New(authConfig.JWT)
+	case authConfig.ClientCert != nil:
+		return certauth.New(authConfig.ClientCert), nil
+	}
 
-	return router
+	// Should not happen, as the app detects this case on config check and exits
+	return nil, fmt.Errorf("missing authenticator type")
 }
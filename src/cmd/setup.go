@@ -3,9 +3,11 @@ package cmd
 import (
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/bdoerfchen/webcmd/src/common/config"
@@ -23,6 +25,9 @@ func mergeCommandFlags(base *config.AppConfig, logger *slog.Logger) {
 		logger.Debug(fmt.Sprintf("flag: port=%v", flagServerPort))
 		base.Server.Port = flagServerPort
 	}
+	if (flagServerHost != "" || flagServerPort != 0) && len(base.Server.Listeners) > 0 {
+		logger.Warn("host and port flags are ignored, as listeners are configured")
+	}
 
 	// --- Add route
 	route := config.DefaultRoute()
@@ -62,7 +67,7 @@ func mergeCommandFlags(base *config.AppConfig, logger *slog.Logger) {
 	}
 
 	// Add route if there is no route defined yet, or it was explicitly configured with flags
-	if len(base.Routes) == 0 || routeTouched {
+	if (len(base.Routes) == 0 && len(base.Groups) == 0) || routeTouched {
 		logger.Debug("adding route provided by command line: " + route.String())
 		base.Routes = append(base.Routes, route)
 	}
@@ -72,15 +77,13 @@ func checkConfig(appConfig *config.AppConfig, logger *slog.Logger) error {
 	logger.Debug("checking configuration")
 
 	var countWarning, countCritical int
-	// Check all routes
-	for _, route := range appConfig.Routes {
-		messages := route.Check()
+	// Log remarks with their respective logging function and increase counters
+	logRemarks := func(subject string, messages config.RouteErrorCollection) {
 		if len(messages) == 0 {
-			continue
+			return
 		}
 
-		// Log remarks with their respective logging function and increase counters
-		logger.Info(fmt.Sprintf("%s with remarks:", route.String()))
+		logger.Info(fmt.Sprintf("%s with remarks:", subject))
 		for _, e := range messages {
 			level := "info: "
 			switch e.Level {
@@ -96,6 +99,46 @@ func checkConfig(appConfig *config.AppConfig, logger *slog.Logger) error {
 		}
 	}
 
+	// Check server
+	logRemarks("server", config.CheckServer(&appConfig.Server))
+
+	// Check modules
+	logRemarks("cors", appConfig.Modules.CORS.Check())
+	logRemarks("rate limit", appConfig.Modules.RateLimit.Check(true))
+	logRemarks("network", appConfig.Modules.CheckNetwork())
+	logRemarks("metrics", appConfig.Modules.Metrics.Check())
+	logRemarks("tracing", appConfig.Modules.Tracing.Check())
+	logRemarks("health", appConfig.Modules.Health.Check())
+	logRemarks("openapi", appConfig.Modules.OpenAPI.Check())
+	logRemarks("admin", appConfig.Modules.Admin.Check(appConfig.Modules.Auth))
+	for _, name := range slices.Sorted(maps.Keys(appConfig.Modules.Auth)) {
+		authenticator := appConfig.Modules.Auth[name]
+		remarks := authenticator.Check()
+		if authenticator.ClientCert != nil && appConfig.Server.ClientAuth == nil {
+			remarks = append(remarks, config.RouteError{Message: "client certificates require 'clientAuth' in the server config", Level: config.ErrorLevelCritical})
+		}
+		logRemarks("authenticator "+name, remarks)
+	}
+
+	// Check all routes
+	for i := range appConfig.Routes {
+		route := &appConfig.Routes[i]
+		logRemarks(route.String(), slices.Concat(route.Check(), route.CheckAuth(appConfig.Modules.Auth), route.CheckListeners(appConfig.Server.Listeners)))
+	}
+
+	// Check all groups and their routes
+	for i := range appConfig.Groups {
+		group := &appConfig.Groups[i]
+		logRemarks(group.String(), group.Check())
+		for j := range group.Routes {
+			route := &group.Routes[j]
+			logRemarks(fmt.Sprintf("%s (%s)", route.String(), group.String()), slices.Concat(route.Check(), route.CheckAuth(appConfig.Modules.Auth), route.CheckListeners(appConfig.Server.Listeners)))
+		}
+	}
+
+	logRemarks("groups", config.CheckGroupPrefixes(appConfig.Routes, appConfig.Groups))
+	logRemarks("routes", config.CheckDuplicateRoutes(appConfig.Routes, appConfig.Groups))
+
 	logger.Debug("configuration check done")
 
 	if countWarning+countCritical == 0 {
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bdoerfchen/webcmd/src/common/params"
+)
+
+var (
+	// The request does not carry credentials for the authenticator
+	ErrNoCredentials = errors.New("no credentials")
+	// The request carries credentials for the authenticator, but they are not valid
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Env variable names of the authenticated principal
+const (
+	EnvUser          = "WC_AUTH_USER"
+	EnvAuthenticator = "WC_AUTH_AUTHENTICATOR"
+)
+
+// The identity of an authenticated request
+type Principal struct {
+	Name          string         // Name of the authenticated user or client
+	Authenticator string         // Name of the authenticator that accepted the request
+	Env           params.EnvMap  // Additional env variables provided by the authenticator
+	Claims        map[string]any // Claims of the principal, like those of a JWT. Available as parameters with source "claim"
+}
+
+// Returns all env variables of the principal
+func (p *Principal) EnvMap() params.EnvMap {
+	env := params.EnvMap{EnvUser: p.Name, EnvAuthenticator: p.Authenticator}
+	for name, value := range p.Env {
+		env[name] = value
+	}
+	return env
+}
+
+type Authenticator interface {
+	// Authenticate a request. Returns [ErrNoCredentials] or [ErrInvalidCredentials] (possibly wrapped) if the request is not accepted
+	Authenticate(req *http.Request) (*Principal, error)
+	// Value of the WWW-Authenticate header sent when no authenticator accepts a request. Empty if there is none
+	Challenge() string
+}
+
+// Named authenticators. Enables to pick the authenticators of a route
+type Collection map[string]Authenticator
+
+// Retrieve the authenticators with the given names
+func (c Collection) For(names []string) ([]Authenticator, error) {
+	result := make([]Authenticator, 0, len(names))
+	for _, name := range names {
+		authenticator, ok := c[name]
+		if !ok {
+			return nil, fmt.Errorf("authenticator '%s' is not defined", name)
+		}
+		result = append(result, authenticator)
+	}
+
+	return result, nil
+}
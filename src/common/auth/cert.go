@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/params"
+)
+
+// Env variable names of a verified client certificate
+const (
+	EnvClientSubject     = "WC_TLS_CLIENT_SUBJECT"
+	EnvClientCommonName  = "WC_TLS_CLIENT_CN"
+	EnvClientSANs        = "WC_TLS_CLIENT_SAN"
+	EnvClientSerial      = "WC_TLS_CLIENT_SERIAL"
+	EnvClientFingerprint = "WC_TLS_CLIENT_FINGERPRINT"
+)
+
+// Returns the client certificate of the request, if it was verified against the client CAs of the server
+func VerifiedClientCertificate(req *http.Request) *x509.Certificate {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return req.TLS.VerifiedChains[0][0]
+}
+
+// Returns the subject alternative names of the certificate: DNS names, email addresses, URIs and IP addresses
+func SubjectAlternativeNames(certificate *x509.Certificate) []string {
+	sans := append([]string{}, certificate.DNSNames...)
+	sans = append(sans, certificate.EmailAddresses...)
+	for _, uri := range certificate.URIs {
+		sans = append(sans, uri.String())
+	}
+	for _, ip := range certificate.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
+
+// Returns the env variables describing the identity of a client certificate
+func ClientCertificateEnv(certificate *x509.Certificate) params.EnvMap {
+	fingerprint := sha256.Sum256(certificate.Raw)
+	return params.EnvMap{
+		EnvClientSubject:     certificate.Subject.String(),
+		EnvClientCommonName:  certificate.Subject.CommonName,
+		EnvClientSANs:        strings.Join(SubjectAlternativeNames(certificate), ","),
+		EnvClientSerial:      certificate.SerialNumber.Text(16),
+		EnvClientFingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+}
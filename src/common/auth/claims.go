@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type principalKey struct{}
+
+// Add the authenticated principal to the context
+func AddPrincipalToContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Get the authenticated principal of the context. Returns nil for unauthenticated requests
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// Returns the string value of a claim. Nested claims are separated by dots (like realm_access.roles).
+// Arrays are joined with commas and objects are encoded as JSON
+func (p *Principal) Claim(path string) (string, bool) {
+	value, ok := p.claim(path)
+	if !ok {
+		return "", false
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case []any:
+		parts := make([]string, len(value))
+		for i, item := range value {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ","), true
+	case map[string]any:
+		content, _ := json.Marshal(value)
+		return string(content), true
+	default:
+		return fmt.Sprint(value), true
+	}
+}
+
+// Returns true if the claim has the value, or contains it if it is an array
+func (p *Principal) HasClaim(path string, expected string) bool {
+	value, ok := p.claim(path)
+	if !ok {
+		return false
+	}
+
+	if values, ok := value.([]any); ok {
+		return slices.ContainsFunc(values, func(item any) bool { return fmt.Sprint(item) == expected })
+	}
+	return fmt.Sprint(value) == expected
+}
+
+// Returns true if the scope is granted by the space separated "scope" claim or the "scp" claim
+func (p *Principal) HasScope(scope string) bool {
+	if scopes, ok := p.claim("scope"); ok {
+		if scopes, ok := scopes.(string); ok && slices.Contains(strings.Fields(scopes), scope) {
+			return true
+		}
+	}
+	if scopes, ok := p.claim("scp"); ok {
+		if scopes, ok := scopes.(string); ok {
+			return slices.Contains(strings.Fields(scopes), scope)
+		}
+		return p.HasClaim("scp", scope)
+	}
+	return false
+}
+
+// Resolve a claim by its dot separated path
+func (p *Principal) claim(path string) (any, bool) {
+	if p == nil || p.Claims == nil {
+		return nil, false
+	}
+	// Claim names may contain dots themselves, like namespaced claims
+	if value, ok := p.Claims[path]; ok {
+		return value, value != nil
+	}
+
+	var current any = p.Claims
+	for name := range strings.SplitSeq(path, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[name]; !ok || current == nil {
+			return nil, false
+		}
+	}
+	return current, true
+}
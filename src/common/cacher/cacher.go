@@ -3,6 +3,8 @@ package cacher
 import "net/http"
 
 type Cacher interface {
-	// Returns a wrapping handler that caches the given HandlerFunc
-	Cache(handler http.HandlerFunc) http.HandlerFunc
+	// Returns a wrapping handler that caches the given HandlerFunc. Its responses are stored under the route name
+	Cache(route string, handler http.HandlerFunc) http.HandlerFunc
+	// Remove the cached responses of a route, or of all routes if the name is empty. Returns the number of removed responses
+	Purge(route string) int
 }
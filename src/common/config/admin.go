@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Admin API for runtime inspection and control, served on its own listener
+type AdminConfig struct {
+	Host   string   // Host of the admin listener. "127.0.0.1" by default
+	Port   uint16   // Port of the admin listener
+	Socket string   // Path of a unix socket to listen on instead of host and port
+	Auth   []string // Names of the authenticators of which one has to accept requests. Only optional on a unix socket
+}
+
+// Perform check on all fields and return a collection of remarks
+func (a *AdminConfig) Check(authenticators map[string]AuthenticatorConfig) (result RouteErrorCollection) {
+	if a == nil {
+		return
+	}
+
+	if a.Host == "" {
+		a.Host = "127.0.0.1"
+	}
+	switch {
+	case a.Socket != "" && a.Port != 0:
+		result = append(result, RouteError{Message: "admin 'port' is ignored with 'socket'", Level: ErrorLevelWarning})
+	case a.Socket == "" && a.Port == 0:
+		result = append(result, RouteError{Message: "admin API requires 'port' or 'socket'", Level: ErrorLevelCritical})
+	}
+	if a.Socket != "" && runtime.GOOS == "windows" {
+		result = append(result, RouteError{Message: "admin 'socket' is not supported on windows", Level: ErrorLevelCritical})
+	}
+
+	if len(a.Auth) == 0 && a.Socket == "" {
+		result = append(result, RouteError{Message: "admin API requires 'auth' unless it listens on a unix socket", Level: ErrorLevelCritical})
+	}
+	for _, name := range a.Auth {
+		if _, ok := authenticators[name]; !ok {
+			result = append(result, RouteError{Message: fmt.Sprintf("authenticator '%s' is not defined in modules", name), Level: ErrorLevelCritical})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+)
+
+// A named authenticator. Exactly one of its types has to be configured
+type AuthenticatorConfig struct {
+	Basic      *AuthBasic      // Basic auth with users of an htpasswd file
+	Bearer     *AuthBearer     // Static bearer tokens
+	JWT        *AuthJWT        // Signed JWT bearer tokens
+	ClientCert *AuthClientCert // Client certificates verified by the server's client auth
+}
+
+type AuthBasic struct {
+	HtpasswdFile string // Path of the htpasswd file. Only bcrypt hashes are supported
+	Realm        string // Realm announced in the WWW-Authenticate header. "webcmd" by default
+}
+
+type AuthBearer struct {
+	TokensFile string // Path of a file with one "name:token" pair per line. Lines starting with # are ignored
+	TokensEnv  string // Name of an env variable with "name:token" pairs, separated by commas or new lines
+	Realm      string // Realm announced in the WWW-Authenticate header. "webcmd" by default
+}
+
+type AuthClientCert struct {
+	Subjects []string // Patterns matched against the common name and the subject of the certificate (like "*.clients.example.com")
+	SANs     []string // Patterns matched against the subject alternative names of the certificate
+}
+
+type AuthJWT struct {
+	Keys      []AuthJWTKey   // Keys to verify token signatures with
+	JWKSFile  string         // Path of a JWKS file with additional keys. Reloaded when the file changes
+	Issuer    string         // Required value of the "iss" claim, if set
+	Audience  string         // Value the "aud" claim has to contain, if set
+	Leeway    timem.Duration // Tolerated clock skew when checking "exp" and "nbf"
+	UserClaim string         // Claim used as name of the principal. "sub" by default
+	Realm     string         // Realm announced in the WWW-Authenticate header. "webcmd" by default
+
+	AllowMissingExp bool // Accept tokens without "exp" claim, which never expire. They are rejected by default
+}
+
+type AuthJWTKey struct {
+	ID            string // Key id matched against the "kid" header of tokens. Keys without id are used for all tokens
+	Algorithm     string // HS256, RS256 or ES256. Derived from the public key if not set
+	Secret        string // Shared secret for HS256
+	SecretEnv     string // Name of an env variable with the shared secret for HS256
+	PublicKeyFile string // Path of a PEM encoded public key or certificate for RS256 and ES256
+}
+
+// Signature algorithms supported for JWT keys and tokens
+var JWTAlgorithms = []string{"HS256", "RS256", "ES256"}
+
+const defaultAuthRealm = "webcmd"
+
+// Returns the names of all configured authenticator types
+func (c *AuthenticatorConfig) Types() (result []string) {
+	if c.Basic != nil {
+		result = append(result, "basic")
+	}
+	if c.Bearer != nil {
+		result = append(result, "bearer")
+	}
+	if c.JWT != nil {
+		result = append(result, "jwt")
+	}
+	if c.ClientCert != nil {
+		result = append(result, "clientCert")
+	}
+
+	return
+}
+
+// Perform check on all fields and return a collection of remarks
+func (c *AuthenticatorConfig) Check() (result RouteErrorCollection) {
+	if types := c.Types(); len(types) == 0 {
+		result = append(result, RouteError{Message: "authenticator requires 'basic', 'bearer', 'jwt' or 'clientCert' config", Level: ErrorLevelCritical})
+	} else if len(types) > 1 {
+		result = append(result, RouteError{Message: fmt.Sprintf("'%s' config will be ignored when providing '%s' config", strings.Join(types[1:], "', '"), types[0]), Level: ErrorLevelWarning})
+	}
+
+	if c.Basic != nil {
+		if c.Basic.HtpasswdFile == "" {
+			result = append(result, RouteError{Message: "htpasswd file must not be empty", Level: ErrorLevelCritical})
+		} else if _, err := os.Stat(c.Basic.HtpasswdFile); err != nil {
+			result = append(result, RouteError{Message: fmt.Sprintf("htpasswd file '%s' can not be read", c.Basic.HtpasswdFile), Level: ErrorLevelCritical})
+		}
+		if c.Basic.Realm == "" {
+			c.Basic.Realm = defaultAuthRealm
+		}
+	}
+
+	if c.Bearer != nil {
+		if c.Bearer.TokensFile == "" && c.Bearer.TokensEnv == "" {
+			result = append(result, RouteError{Message: "bearer requires 'tokensFile' or 'tokensEnv'", Level: ErrorLevelCritical})
+		}
+		if c.Bearer.TokensFile != "" {
+			if _, err := os.Stat(c.Bearer.TokensFile); err != nil {
+				result = append(result, RouteError{Message: fmt.Sprintf("tokens file '%s' can not be read", c.Bearer.TokensFile), Level: ErrorLevelCritical})
+			}
+		}
+		if c.Bearer.TokensEnv != "" {
+			if _, ok := os.LookupEnv(c.Bearer.TokensEnv); !ok {
+				result = append(result, RouteError{Message: fmt.Sprintf("tokens env variable '%s' is not set", c.Bearer.TokensEnv), Level: ErrorLevelWarning})
+			}
+		}
+		if c.Bearer.Realm == "" {
+			c.Bearer.Realm = defaultAuthRealm
+		}
+	}
+
+	if c.JWT != nil {
+		result = append(result, c.JWT.check()...)
+	}
+
+	if c.ClientCert != nil {
+		for _, pattern := range slices.Concat(c.ClientCert.Subjects, c.ClientCert.SANs) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				result = append(result, RouteError{Message: fmt.Sprintf("invalid client certificate pattern '%s'", pattern), Level: ErrorLevelCritical})
+			}
+		}
+		if len(c.ClientCert.Subjects) == 0 && len(c.ClientCert.SANs) == 0 {
+			result = append(result, RouteError{Message: "client certificate authenticator accepts every certificate of the client CAs", Level: ErrorLevelInfo})
+		}
+	}
+
+	return
+}
+
+func (c *AuthJWT) check() (result RouteErrorCollection) {
+	if len(c.Keys) == 0 && c.JWKSFile == "" {
+		result = append(result, RouteError{Message: "jwt requires 'keys' or 'jwksFile'", Level: ErrorLevelCritical})
+	}
+	for i, key := range c.Keys {
+		if key.Algorithm != "" && !slices.Contains(JWTAlgorithms, key.Algorithm) {
+			result = append(result, RouteError{Message: fmt.Sprintf("jwt key %v has unsupported algorithm '%s'", i+1, key.Algorithm), Level: ErrorLevelCritical})
+		}
+		hasSecret := key.Secret != "" || key.SecretEnv != ""
+		if hasSecret == (key.PublicKeyFile != "") {
+			result = append(result, RouteError{Message: fmt.Sprintf("jwt key %v requires either a secret or 'publicKeyFile'", i+1), Level: ErrorLevelCritical})
+		} else if hasSecret && key.Algorithm != "" && key.Algorithm != "HS256" {
+			result = append(result, RouteError{Message: fmt.Sprintf("jwt key %v with secret only supports HS256", i+1), Level: ErrorLevelCritical})
+		}
+		if key.Secret != "" {
+			result = append(result, RouteError{Message: fmt.Sprintf("jwt key %v has its secret in the config, consider 'secretEnv'", i+1), Level: ErrorLevelInfo})
+		}
+		if key.SecretEnv != "" {
+			if _, ok := os.LookupEnv(key.SecretEnv); !ok {
+				result = append(result, RouteError{Message: fmt.Sprintf("secret env variable '%s' is not set", key.SecretEnv), Level: ErrorLevelCritical})
+			}
+		}
+	}
+	if c.JWKSFile != "" {
+		if _, err := os.Stat(c.JWKSFile); err != nil {
+			result = append(result, RouteError{Message: fmt.Sprintf("jwks file '%s' can not be read", c.JWKSFile), Level: ErrorLevelCritical})
+		}
+	}
+	if c.Issuer == "" || c.Audience == "" {
+		result = append(result, RouteError{Message: "jwt without 'issuer' and 'audience' accepts tokens issued for other services", Level: ErrorLevelInfo})
+	}
+	if c.UserClaim == "" {
+		c.UserClaim = "sub"
+	}
+	if c.Realm == "" {
+		c.Realm = defaultAuthRealm
+	}
+
+	return
+}
+
+// Check that all authenticators referenced by the route are defined
+func (r *Route) CheckAuth(authenticators map[string]AuthenticatorConfig) (result RouteErrorCollection) {
+	for _, name := range r.Auth {
+		if _, ok := authenticators[name]; !ok {
+			result = append(result, RouteError{Message: fmt.Sprintf("authenticator '%s' is not defined in modules", name), Level: ErrorLevelCritical})
+		}
+	}
+
+	if (len(r.RequireScopes) > 0 || len(r.RequireClaims) > 0) && len(r.Auth) == 0 {
+		result = append(result, RouteError{Message: "required scopes and claims need 'auth' to be set", Level: ErrorLevelCritical})
+	}
+
+	return
+}
@@ -8,6 +8,8 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/process"
 )
 
 // Perform check on all fields and return a collection of remarks
@@ -18,8 +20,8 @@ func (r *Route) Check() (result RouteErrorCollection) {
 		result = append(result, RouteError{Message: fmt.Sprintf("http method '%s' is not allowed", r.Method), Level: ErrorLevelCritical})
 	}
 
-	// Add info when body is not allowed for POST or PUT route
-	if (r.Method == http.MethodPost || r.Method == http.MethodPut) && !r.AllowBody {
+	// Add info when body is not allowed for POST, PUT or PATCH route
+	if (r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch) && !r.AllowBody {
 		result = append(result, RouteError{Message: "body will be ignored", Level: ErrorLevelInfo})
 	}
 
@@ -33,14 +35,38 @@ func (r *Route) Check() (result RouteErrorCollection) {
 
 	// Check caching
 	if r.Caching && r.Method != http.MethodGet {
-		result = append(result, RouteError{Message: "caching only works on GET requests", Level: ErrorLevelWarning})
+		result = append(result, RouteError{Message: "caching only works on GET requests (and HEAD requests answered by them)", Level: ErrorLevelWarning})
+	}
+
+	// Check cors
+	result = append(result, r.CORS.Check()...)
+
+	// Check rate limit
+	result = append(result, r.RateLimit.Check(false)...)
+
+	// Check client address lists
+	result = append(result, checkAddressLists(r.AllowFrom, r.DenyFrom)...)
+
+	// Check webhook
+	result = append(result, r.Webhook.Check()...)
+	if r.Webhook != nil && r.Method == http.MethodGet {
+		result = append(result, RouteError{Message: "webhooks are delivered with POST requests", Level: ErrorLevelWarning})
 	}
 
+	// Check health check
+	result = append(result, r.HealthCheck.Check()...)
+
+	// Check route kind
+	if kinds := r.Kinds(); len(kinds) == 0 {
+		result = append(result, RouteError{Message: "route requires 'exec' config with 'proc', 'shell', 'fastcgi', 'ssh' or 'pipeline', or one of 'static', 'redirect', 'files' or 'proxy' config", Level: ErrorLevelCritical})
+	} else if len(kinds) > 1 {
+		result = append(result, RouteError{Message: fmt.Sprintf("'%s' config will be ignored when providing '%s' config", strings.Join(kinds[1:], "', '"), kinds[0]), Level: ErrorLevelWarning})
+	}
+	result = append(result, r.checkKinds()...)
+
 	// Check exec
-	if r.Exec.Proc == nil && r.Exec.Shell == nil {
-		result = append(result, RouteError{Message: "exec requires 'proc' or 'shell' config", Level: ErrorLevelCritical})
-	} else if r.Exec.Proc != nil && r.Exec.Shell != nil {
-		result = append(result, RouteError{Message: "'shell' config will be ignored when providing 'proc' config", Level: ErrorLevelWarning})
+	if modes := r.Exec.Modes(); len(modes) > 1 {
+		result = append(result, RouteError{Message: fmt.Sprintf("'%s' config will be ignored when providing '%s' config", strings.Join(modes[1:], "', '"), modes[0]), Level: ErrorLevelWarning})
 	}
 
 	// Check exec.proc
@@ -54,18 +80,64 @@ func (r *Route) Check() (result RouteErrorCollection) {
 		if r.Exec.Shell.Command == "" {
 			result = append(result, RouteError{Message: "shell command must not be empty", Level: ErrorLevelCritical})
 		}
+	} else if r.Exec.FastCGI != nil {
+		if r.Exec.FastCGI.Address == "" {
+			result = append(result, RouteError{Message: "fastcgi address must not be empty", Level: ErrorLevelCritical})
+		}
+		if r.Exec.FastCGI.Script == "" {
+			result = append(result, RouteError{Message: "fastcgi script is empty, SCRIPT_FILENAME will not be set", Level: ErrorLevelInfo})
+		}
+	} else if r.Exec.SSH != nil {
+		if r.Exec.SSH.Host == "" || r.Exec.SSH.User == "" {
+			result = append(result, RouteError{Message: "ssh host and user must not be empty", Level: ErrorLevelCritical})
+		}
+		if r.Exec.SSH.KeyFile == "" {
+			result = append(result, RouteError{Message: "ssh key file must not be empty", Level: ErrorLevelCritical})
+		}
+		if r.Exec.SSH.Command == "" {
+			result = append(result, RouteError{Message: "ssh command must not be empty", Level: ErrorLevelCritical})
+		}
+		if r.Exec.SSH.InsecureIgnoreHostKey {
+			result = append(result, RouteError{Message: "ssh host key verification is disabled", Level: ErrorLevelWarning})
+		}
+	} else if len(r.Exec.Pipeline) > 0 {
+		for i, step := range r.Exec.Pipeline {
+			if step.Path == "" {
+				result = append(result, RouteError{Message: fmt.Sprintf("pipeline step %v: executable path must not be empty", i+1), Level: ErrorLevelCritical})
+			} else if _, err := exec.LookPath(step.Path); err != nil {
+				result = append(result, RouteError{Message: fmt.Sprintf("pipeline step %v: executable '%s' can not be found as file or on PATH", i+1, step.Path), Level: ErrorLevelWarning})
+			}
+		}
 	}
 
 	// Check exit codes
 	for _, codeMapping := range r.StatusCodes {
 		// Check for valid response stream names
 		if !codeMapping.ResponseStream.IsValid() {
-			result = append(result, RouteError{Message: fmt.Sprintf("exit code %v with invalid response stream '%s'", *codeMapping.ExitCode, codeMapping.ResponseStream), Level: ErrorLevelCritical})
+			result = append(result, RouteError{Message: fmt.Sprintf("%s with invalid response stream '%s'", codeMapping.String(), codeMapping.ResponseStream), Level: ErrorLevelCritical})
+		}
+
+		// Check conditions
+		if codeMapping.ExitCodes != nil && codeMapping.ExitCodes.From > codeMapping.ExitCodes.To {
+			result = append(result, RouteError{Message: fmt.Sprintf("exit code range %s is empty", codeMapping.ExitCodes), Level: ErrorLevelCritical})
 		}
+		if codeMapping.Signal != "" && !process.IsKnownSignal(codeMapping.Signal) {
+			result = append(result, RouteError{Message: fmt.Sprintf("signal '%s' is unknown and might never match", codeMapping.Signal), Level: ErrorLevelWarning})
+		}
+		for _, pattern := range []string{codeMapping.Stdout, codeMapping.Stderr} {
+			if _, err := regexp.Compile(pattern); err != nil {
+				result = append(result, RouteError{Message: fmt.Sprintf("invalid output pattern '%s': %s", pattern, err.Error()), Level: ErrorLevelCritical})
+			}
+		}
+	}
+
+	// Check error format
+	if !slices.Contains(allowedErrorFormats, r.ErrorFormat) {
+		result = append(result, RouteError{Message: fmt.Sprintf("invalid error format '%s'", r.ErrorFormat), Level: ErrorLevelCritical})
 	}
 
 	// Check default status code
-	if !slices.ContainsFunc(r.StatusCodes, func(i ExitCodeMapping) bool { return i.ExitCode == nil }) {
+	if r.Kind() == "exec" && !slices.ContainsFunc(r.StatusCodes, func(i ExitCodeMapping) bool { return i.IsDefault() }) {
 		result = append(result, RouteError{Message: "no default status code for non-zero exit codes defined: uses 500 now", Level: ErrorLevelInfo})
 	}
 
@@ -81,6 +153,11 @@ func (r *Route) Check() (result RouteErrorCollection) {
 			result = append(result, RouteError{Message: fmt.Sprintf("param '%s' has invalid source: %s", param.Name, param.Source), Level: ErrorLevelCritical})
 		}
 
+		// Claims are only available on authenticated routes
+		if param.Source == ParamSourceClaim && len(r.Auth) == 0 {
+			result = append(result, RouteError{Message: fmt.Sprintf("claim param '%s' is always empty without 'auth'", param.Name), Level: ErrorLevelWarning})
+		}
+
 		// Check "as" is valid
 		if param.As != "" && !validEnvName.MatchString(param.As) {
 			result = append(result, RouteError{Message: fmt.Sprintf("param '%s' has invalid redefined env variable name: %s", param.Name, param.As), Level: ErrorLevelCritical})
@@ -10,16 +10,23 @@ import (
 type AppConfig struct {
 	Server  server.Config // All http server related configurations
 	Routes  []Route       // A list of routes to serve
+	Groups  []RouteGroup  // A list of route groups with shared prefix and defaults
 	Modules ModulesConfig // Configuration regarding additional server-wide functionality
 }
 
 func DefaultAppConfig() AppConfig {
 	return AppConfig{
 		Server: server.Config{
-			Host: "0.0.0.0",
-			Port: 8080,
+			Host:         "0.0.0.0",
+			Port:         8080,
+			DrainTimeout: timem.Duration(30 * time.Second),
+
+			ReadHeaderTimeout: timem.Duration(10 * time.Second),
+			IdleTimeout:       timem.Duration(2 * time.Minute),
+			MaxHeaderBytes:    1 << 20,
 		},
 		Routes: make([]Route, 0),
+		Groups: make([]RouteGroup, 0),
 		Modules: ModulesConfig{
 			ShellPool: ShellPoolConfig{
 				Path: "/usr/bin/bash",
@@ -30,6 +37,13 @@ func DefaultAppConfig() AppConfig {
 				MaxResponsesCached: 100,
 				TTL:                timem.Duration(10 * time.Minute),
 			},
+			RequestID: RequestIDConfig{
+				Header: DefaultRequestIDHeader,
+			},
+			Health: HealthConfig{
+				LivenessPath:  DefaultLivenessPath,
+				ReadinessPath: DefaultReadinessPath,
+			},
 		},
 	}
 }
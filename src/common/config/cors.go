@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+)
+
+// Cross-origin resource sharing config. Used server-wide in the modules config, or per route to replace it
+type CORSConfig struct {
+	AllowedOrigins   []string       // Origins allowed to access routes. Supports wildcards (like https://*.example.com) and "*" for all origins. CORS is disabled without origins
+	AllowedMethods   []string       // Methods allowed in preflight requests. The method of the route by default
+	AllowedHeaders   []string       // Request headers allowed in preflight requests, or "*" for all headers
+	ExposedHeaders   []string       // Response headers readable by the browser
+	AllowCredentials bool           // Allow requests with credentials like cookies. The origin is always answered explicitly then
+	MaxAge           timem.Duration // Time a preflight response may be cached by the browser
+}
+
+// Returns true if CORS is enabled by this config
+func (c *CORSConfig) Enabled() bool {
+	return c != nil && len(c.AllowedOrigins) > 0
+}
+
+// Returns true if the origin matches one of the allowed origins
+func (c *CORSConfig) AllowsOrigin(origin string) bool {
+	return slices.ContainsFunc(c.AllowedOrigins, func(pattern string) bool {
+		if pattern == "*" {
+			return true
+		}
+		matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(origin))
+		return matched
+	})
+}
+
+// Perform check on all fields and return a collection of remarks
+func (c *CORSConfig) Check() (result RouteErrorCollection) {
+	if !c.Enabled() {
+		return
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		if _, err := path.Match(origin, ""); err != nil {
+			result = append(result, RouteError{Message: fmt.Sprintf("invalid cors origin pattern '%s'", origin), Level: ErrorLevelCritical})
+		} else if origin == "*" && c.AllowCredentials {
+			result = append(result, RouteError{Message: "cors allows credentialed requests from any origin", Level: ErrorLevelWarning})
+		}
+	}
+
+	for i, method := range c.AllowedMethods {
+		c.AllowedMethods[i] = strings.ToUpper(method)
+		if !slices.Contains(allowedMethods, c.AllowedMethods[i]) {
+			result = append(result, RouteError{Message: fmt.Sprintf("cors method '%s' is not allowed", method), Level: ErrorLevelCritical})
+		}
+	}
+
+	if c.MaxAge < 0 {
+		result = append(result, RouteError{Message: "cors max age must not be negative", Level: ErrorLevelCritical})
+	}
+
+	return
+}
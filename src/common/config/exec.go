@@ -1,8 +1,11 @@
 package config
 
 type RouteExec struct {
-	Proc  *ExecProc
-	Shell *ExecShell
+	Proc     *ExecProc
+	Shell    *ExecShell
+	FastCGI  *ExecFastCGI
+	SSH      *ExecSSH
+	Pipeline []ExecProc // Processes with each stdout connected to the next one's stdin
 }
 
 type ExecProc struct {
@@ -13,3 +16,38 @@ type ExecProc struct {
 type ExecShell struct {
 	Command string // Shell command
 }
+
+type ExecFastCGI struct {
+	Address string // Address of the FastCGI application. Either host:port for tcp, or a socket path (optionally prefixed with "unix:")
+	Script  string // Script path sent as SCRIPT_FILENAME to the application
+}
+
+type ExecSSH struct {
+	Host                  string // Remote host, optionally with port. Port 22 is used by default
+	User                  string // Login user on the remote host
+	KeyFile               string // Path to the private key file used for authentication
+	KnownHosts            string // Path to the known_hosts file to verify the host key against. Defaults to ~/.ssh/known_hosts
+	InsecureIgnoreHostKey bool   // Skip host key verification. Should only be used for testing
+	Command               string // Command to run on the remote host
+}
+
+// Returns the names of all configured exec modes, in the order of their precedence
+func (e *RouteExec) Modes() (result []string) {
+	if e.Proc != nil {
+		result = append(result, "proc")
+	}
+	if e.Shell != nil {
+		result = append(result, "shell")
+	}
+	if e.FastCGI != nil {
+		result = append(result, "fastcgi")
+	}
+	if e.SSH != nil {
+		result = append(result, "ssh")
+	}
+	if len(e.Pipeline) > 0 {
+		result = append(result, "pipeline")
+	}
+
+	return
+}
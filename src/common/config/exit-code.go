@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// An inclusive range of exit codes. Can be parsed from a single number or a "from-to" string (like "10-19" or "-5--1")
+type ExitCodeRange struct {
+	From int
+	To   int
+}
+
+func (r ExitCodeRange) Contains(code int) bool {
+	return code >= r.From && code <= r.To
+}
+
+func (r ExitCodeRange) String() string {
+	if r.From == r.To {
+		return strconv.Itoa(r.From)
+	}
+	return fmt.Sprintf("%v-%v", r.From, r.To)
+}
+
+func (r ExitCodeRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *ExitCodeRange) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case float64:
+		r.From, r.To = int(value), int(value)
+		return nil
+	case string:
+		// A leading sign belongs to the first number, so negative codes can be given (like "-5--1")
+		unsigned := strings.TrimSpace(value)
+		sign := ""
+		if strings.HasPrefix(unsigned, "-") {
+			sign, unsigned = "-", unsigned[1:]
+		}
+		from, to, isRange := strings.Cut(unsigned, "-")
+		from = sign + from
+		if !isRange {
+			to = from
+		}
+
+		var err error
+		if r.From, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+			return fmt.Errorf("invalid exit code range '%s'", value)
+		}
+		if r.To, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+			return fmt.Errorf("invalid exit code range '%s'", value)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid exit code range")
+	}
+}
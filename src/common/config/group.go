@@ -0,0 +1,89 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// A group of routes sharing a path prefix and defaults that are merged into each member route
+type RouteGroup struct {
+	Prefix      string            // Path prefix for all routes of this group
+	Headers     map[string]string // Default response headers. Headers of a route take precedence
+	Parameters  []RouteParameter  // Parameters added in front of each route's parameters. Route parameters with the same env name take precedence
+	StatusCodes []ExitCodeMapping // Exit code mappings evaluated after the mappings of each route
+	Listeners   []string          // Names of the listeners serving routes without their own listeners
+	Routes      []Route           // Routes of this group. Their patterns are relative to the prefix
+}
+
+// Perform check on the group itself and return a collection of remarks. Routes are checked separately
+func (g *RouteGroup) Check() (result RouteErrorCollection) {
+	if g.Prefix == "" || g.Prefix == "/" {
+		result = append(result, RouteError{Message: "group prefix must not be empty", Level: ErrorLevelCritical})
+	} else if g.Prefix[0] != '/' {
+		g.Prefix = "/" + g.Prefix
+	}
+	g.Prefix = strings.TrimSuffix(g.Prefix, "/")
+
+	if strings.ContainsAny(g.Prefix, "{}*") {
+		result = append(result, RouteError{Message: "group prefix must not contain parameters or wildcards", Level: ErrorLevelCritical})
+	}
+
+	if len(g.Routes) == 0 {
+		result = append(result, RouteError{Message: "group has no routes", Level: ErrorLevelWarning})
+	}
+
+	return
+}
+
+// Check that the prefixes of the groups are unique and not used by top-level routes, as groups are mounted on their prefix.
+// Expects the groups to be checked already
+func CheckGroupPrefixes(routes []Route, groups []RouteGroup) (result RouteErrorCollection) {
+	prefixes := make(map[string]bool)
+	for _, group := range groups {
+		if prefixes[group.Prefix] {
+			result = append(result, RouteError{Message: fmt.Sprintf("group prefix '%s' is used by more than one group", group.Prefix), Level: ErrorLevelCritical})
+		}
+		prefixes[group.Prefix] = true
+	}
+
+	for _, route := range routes {
+		pattern := strings.TrimSuffix(route.Route, "/")
+		for prefix := range prefixes {
+			if pattern == prefix || strings.HasPrefix(pattern, prefix+"/") {
+				result = append(result, RouteError{Message: fmt.Sprintf("%s is within the prefix of group %s, define it in the group instead", route.String(), prefix), Level: ErrorLevelCritical})
+			}
+		}
+	}
+
+	return
+}
+
+// Check that no two routes share method and pattern, including the prefix of their group. Such routes would replace each other,
+// even if they are served on different listeners. Expects the routes and groups to be checked already
+func CheckDuplicateRoutes(routes []Route, groups []RouteGroup) (result RouteErrorCollection) {
+	seen := make(map[string]bool)
+	add := func(prefix string, route Route) {
+		name := route.Method + " " + prefix + cmp.Or(strings.TrimSuffix(route.Route, "/"), "/")
+		if seen[name] {
+			result = append(result, RouteError{Message: fmt.Sprintf("route %s is defined more than once, routes on different listeners need different patterns", name), Level: ErrorLevelCritical})
+		}
+		seen[name] = true
+	}
+
+	for _, route := range routes {
+		add("", route)
+	}
+	for _, group := range groups {
+		for _, route := range group.Routes {
+			add(group.Prefix, route)
+		}
+	}
+
+	return
+}
+
+// Prints "group PREFIX" (example: group /api)
+func (g *RouteGroup) String() string {
+	return fmt.Sprintf("group %s", g.Prefix)
+}
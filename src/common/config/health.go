@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+)
+
+const (
+	DefaultLivenessPath  = "/healthz"
+	DefaultReadinessPath = "/readyz"
+)
+
+// Built-in health endpoints for liveness and readiness probes
+type HealthConfig struct {
+	LivenessPath  string        // Path answering as long as the process is alive. "/healthz" by default
+	ReadinessPath string        // Path answering if webcmd is ready to serve requests. "/readyz" by default
+	AccessLog     bool          // Log requests of the health endpoints. Disabled by default
+	Checks        []HealthCheck // Commands run periodically, which all have to succeed for readiness
+}
+
+// A command run periodically, whose exit code decides about readiness
+type HealthCheck struct {
+	Name     string         // Name shown in readiness responses. Routes use their method and pattern
+	Path     string         // Process path
+	Args     []string       // List of arguments for the command
+	Interval timem.Duration // Time between two runs. 30s by default
+	Timeout  timem.Duration // Time the command may take before it counts as failed. 5s by default
+}
+
+// Perform check on all fields and return a collection of remarks
+func (h *HealthConfig) Check() (result RouteErrorCollection) {
+	for _, field := range []*string{&h.LivenessPath, &h.ReadinessPath} {
+		if *field != "" && !strings.HasPrefix(*field, "/") {
+			*field = "/" + *field
+		}
+	}
+	if h.LivenessPath != "" && h.LivenessPath == h.ReadinessPath {
+		result = append(result, RouteError{Message: fmt.Sprintf("liveness and readiness path must not be the same '%s'", h.LivenessPath), Level: ErrorLevelCritical})
+	}
+
+	for i := range h.Checks {
+		if h.Checks[i].Name == "" {
+			h.Checks[i].Name = h.Checks[i].Path
+		}
+		for _, remark := range h.Checks[i].Check() {
+			remark.Message = fmt.Sprintf("check '%s': %s", h.Checks[i].Name, remark.Message)
+			result = append(result, remark)
+		}
+	}
+
+	return
+}
+
+// Perform check on all fields and return a collection of remarks
+func (c *HealthCheck) Check() (result RouteErrorCollection) {
+	if c == nil {
+		return
+	}
+
+	if c.Path == "" {
+		result = append(result, RouteError{Message: "health check path must not be empty", Level: ErrorLevelCritical})
+	} else if _, err := exec.LookPath(c.Path); err != nil {
+		result = append(result, RouteError{Message: fmt.Sprintf("health check executable '%s' can not be found as file or on PATH", c.Path), Level: ErrorLevelWarning})
+	}
+	if c.Interval <= 0 {
+		c.Interval = timem.Duration(30 * time.Second)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = timem.Duration(5 * time.Second)
+	}
+	if c.Timeout > c.Interval {
+		result = append(result, RouteError{Message: "health check timeout is longer than its interval", Level: ErrorLevelWarning})
+	}
+
+	return
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"strings"
+)
+
+// Prometheus metrics endpoint
+type MetricsConfig struct {
+	Path string // Path of the metrics endpoint. "/metrics" by default
+	Host string // Host of a separate listener for the metrics endpoint
+	Port uint16 // Port of a separate listener for the metrics endpoint. Served by the main server if not set
+}
+
+// Returns true if the metrics are served by their own listener instead of the main server
+func (m *MetricsConfig) Separate() bool {
+	return m.Port != 0
+}
+
+// Perform check on all fields and return a collection of remarks
+func (m *MetricsConfig) Check() (result RouteErrorCollection) {
+	if m == nil {
+		return
+	}
+
+	if m.Path == "" {
+		m.Path = "/metrics"
+	} else if !strings.HasPrefix(m.Path, "/") {
+		m.Path = "/" + m.Path
+	}
+	if m.Host != "" && !m.Separate() {
+		result = append(result, RouteError{Message: "metrics 'host' is ignored without 'port'", Level: ErrorLevelWarning})
+	}
+
+	return
+}
@@ -7,6 +7,25 @@ import (
 type ModulesConfig struct {
 	ShellPool ShellPoolConfig
 	Cache     CacheConfig
+	CORS      *CORSConfig                    // Server-wide CORS config. Disabled by default
+	Auth      map[string]AuthenticatorConfig // Named authenticators referenced by routes
+	RateLimit *RateLimit                     // Global rate limit shared by all routes. Disabled by default
+	Metrics   *MetricsConfig                 // Prometheus metrics endpoint. Disabled by default
+	Tracing   *TracingConfig                 // Export of trace spans over OTLP/HTTP. Disabled by default
+	RequestID RequestIDConfig                // Identification of requests in logs, env and responses
+	Health    HealthConfig                   // Built-in liveness and readiness endpoints
+	OpenAPI   *OpenAPIConfig                 // Serve an OpenAPI document of the routes. Disabled by default
+	Admin     *AdminConfig                   // Admin API on its own listener. Disabled by default
+
+	AllowFrom      []string // CIDR ranges of clients allowed to access any route. All clients by default
+	DenyFrom       []string // CIDR ranges of clients denied to access any route
+	TrustedProxies []string // CIDR ranges of proxies whose X-Forwarded-For and X-Real-IP headers are honoured. None by default
+}
+
+const DefaultRequestIDHeader = "X-Request-Id"
+
+type RequestIDConfig struct {
+	Header string // Header the request ID is accepted from and returned in. X-Request-Id by default
 }
 
 type ShellPoolConfig struct {
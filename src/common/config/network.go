@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Parse a list of CIDR ranges (like 10.0.0.0/8). Single addresses are treated as ranges of only this address
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, entry := range list {
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address '%s'", entry)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr range '%s'", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// Returns true if the address is in one of the ranges
+func ContainsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Perform check on the address lists of a route or the server and return a collection of remarks
+func checkAddressLists(allowFrom, denyFrom []string) (result RouteErrorCollection) {
+	if _, err := ParsePrefixes(allowFrom); err != nil {
+		result = append(result, RouteError{Message: "allowFrom: " + err.Error(), Level: ErrorLevelCritical})
+	}
+	if _, err := ParsePrefixes(denyFrom); err != nil {
+		result = append(result, RouteError{Message: "denyFrom: " + err.Error(), Level: ErrorLevelCritical})
+	}
+	return
+}
+
+// Perform check on the client address related fields and return a collection of remarks
+func (m *ModulesConfig) CheckNetwork() (result RouteErrorCollection) {
+	result = checkAddressLists(m.AllowFrom, m.DenyFrom)
+	if _, err := ParsePrefixes(m.TrustedProxies); err != nil {
+		result = append(result, RouteError{Message: "trustedProxies: " + err.Error(), Level: ErrorLevelCritical})
+	}
+	return
+}
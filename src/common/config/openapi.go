@@ -0,0 +1,29 @@
+package config
+
+import "strings"
+
+// OpenAPI document served by the server
+type OpenAPIConfig struct {
+	Path        string // Path of the document. "/openapi.json" by default
+	Title       string // Title of the API. "webcmd" by default
+	Version     string // Version of the API. The webcmd version by default
+	Description string // Description of the API
+}
+
+// Perform check on all fields and return a collection of remarks
+func (o *OpenAPIConfig) Check() (result RouteErrorCollection) {
+	if o == nil {
+		return
+	}
+
+	if o.Path == "" {
+		o.Path = "/openapi.json"
+	} else if !strings.HasPrefix(o.Path, "/") {
+		o.Path = "/" + o.Path
+	}
+	if o.Title == "" {
+		o.Title = "webcmd"
+	}
+
+	return
+}
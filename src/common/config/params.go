@@ -15,7 +15,8 @@ const (
 	ParamSourceQuery  ParamSource = "query"
 	ParamSourceRoute  ParamSource = "route"
 	ParamSourceHeader ParamSource = "header"
+	ParamSourceClaim  ParamSource = "claim" // Claim of the authenticated principal, nested claims are separated by dots
 	ParamSourceNone   ParamSource = ""
 )
 
-var allowedParamSources = []ParamSource{ParamSourceQuery, ParamSourceRoute, ParamSourceHeader, ParamSourceNone}
+var allowedParamSources = []ParamSource{ParamSourceQuery, ParamSourceRoute, ParamSourceHeader, ParamSourceClaim, ParamSourceNone}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+)
+
+// A token bucket rate limit. Each key gets a bucket of Burst requests, which is refilled with Requests per Period
+type RateLimit struct {
+	Requests  uint           // Number of requests allowed per period
+	Period    timem.Duration // Period in which the requests are refilled. 1m by default
+	Burst     uint           // Maximum number of requests at once. Requests by default
+	Key       RateLimitKey   // What requests are counted by. Client IP by default
+	Parameter string         // Env variable name of the route parameter whose value is the key, for key "parameter"
+}
+
+type RateLimitKey string
+
+const (
+	RateLimitKeyIP        RateLimitKey = "ip"        // Client IP address
+	RateLimitKeyPrincipal RateLimitKey = "principal" // Authenticated principal, client IP for unauthenticated requests
+	RateLimitKeyParameter RateLimitKey = "parameter" // Value of a route parameter, client IP if it is empty
+)
+
+var allowedRateLimitKeys = []RateLimitKey{RateLimitKeyIP, RateLimitKeyPrincipal, RateLimitKeyParameter}
+
+// Perform check on all fields and return a collection of remarks. Global limits can not be keyed by parameters
+func (l *RateLimit) Check(global bool) (result RouteErrorCollection) {
+	if l == nil {
+		return
+	}
+
+	if l.Requests == 0 {
+		result = append(result, RouteError{Message: "rate limit requires 'requests' greater than 0", Level: ErrorLevelCritical})
+	}
+	if l.Period == 0 {
+		l.Period = timem.Duration(time.Minute)
+	} else if l.Period < 0 {
+		result = append(result, RouteError{Message: "rate limit period must not be negative", Level: ErrorLevelCritical})
+	}
+	if l.Burst == 0 {
+		l.Burst = l.Requests
+	}
+
+	if l.Key == "" {
+		l.Key = RateLimitKeyIP
+	} else if !slices.Contains(allowedRateLimitKeys, l.Key) {
+		result = append(result, RouteError{Message: fmt.Sprintf("invalid rate limit key '%s'", l.Key), Level: ErrorLevelCritical})
+	}
+	if l.Key == RateLimitKeyParameter {
+		if global {
+			result = append(result, RouteError{Message: "global rate limit can not be keyed by a parameter", Level: ErrorLevelCritical})
+		} else if l.Parameter == "" {
+			result = append(result, RouteError{Message: "rate limit key 'parameter' requires 'parameter'", Level: ErrorLevelCritical})
+		}
+	}
+
+	return
+}
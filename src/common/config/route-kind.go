@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Route kinds that do not execute anything
+type RouteStatic struct {
+	StatusCode int    // Response status code. 200 by default
+	Body       string // Fixed response body
+}
+
+type RouteRedirect struct {
+	StatusCode int    // Redirect status code. 302 by default
+	Location   string // Location template. Parameter env variables are expanded (like /users/${WC_ID})
+}
+
+type RouteFiles struct {
+	Directory string // Directory to serve. The route pattern should end with a wildcard to serve its content
+	Index     string // Name of the index file served for directories. "index.html" by default
+	Listing   bool   // Enable directory listings when there is no index file
+	Hidden    bool   // Serve hidden files and directories, whose names start with a dot
+}
+
+type RouteProxy struct {
+	Upstream     string            // URL of the upstream server. The wildcard part of the route is appended to its path
+	Headers      map[string]string // Request headers to set for the upstream request. Empty values remove the header
+	PreserveHost bool              // Forward the original Host header instead of the upstream host
+	ForwardAuth  bool              // Forward the Authorization header of routes with auth. It is removed by default
+}
+
+// Returns the names of all configured route kinds, in the order of their precedence
+func (r *Route) Kinds() (result []string) {
+	if len(r.Exec.Modes()) > 0 {
+		result = append(result, "exec")
+	}
+	if r.Static != nil {
+		result = append(result, "static")
+	}
+	if r.Redirect != nil {
+		result = append(result, "redirect")
+	}
+	if r.Files != nil {
+		result = append(result, "files")
+	}
+	if r.Proxy != nil {
+		result = append(result, "proxy")
+	}
+
+	return
+}
+
+// Returns the kind used for this route, or an empty string if it has none
+func (r *Route) Kind() string {
+	if kinds := r.Kinds(); len(kinds) > 0 {
+		return kinds[0]
+	}
+	return ""
+}
+
+// Check the configuration of the route kinds that do not execute anything
+func (r *Route) checkKinds() (result RouteErrorCollection) {
+	switch r.Kind() {
+	case "static":
+		if r.Static.StatusCode == 0 {
+			r.Static.StatusCode = http.StatusOK
+		} else if r.Static.StatusCode < http.StatusOK || r.Static.StatusCode > 999 {
+			result = append(result, RouteError{Message: fmt.Sprintf("invalid static status code %v", r.Static.StatusCode), Level: ErrorLevelCritical})
+		}
+	case "redirect":
+		if r.Redirect.StatusCode == 0 {
+			r.Redirect.StatusCode = http.StatusFound
+		} else if r.Redirect.StatusCode < http.StatusMultipleChoices || r.Redirect.StatusCode >= http.StatusBadRequest {
+			result = append(result, RouteError{Message: fmt.Sprintf("redirect status code %v is not a 3xx code", r.Redirect.StatusCode), Level: ErrorLevelCritical})
+		}
+		if r.Redirect.Location == "" {
+			result = append(result, RouteError{Message: "redirect location must not be empty", Level: ErrorLevelCritical})
+		} else if strings.HasPrefix(r.Redirect.Location, "$") {
+			result = append(result, RouteError{Message: "redirect location starts with a parameter and allows redirects to any location", Level: ErrorLevelWarning})
+		}
+	case "files":
+		if r.Files.Directory == "" {
+			result = append(result, RouteError{Message: "files directory must not be empty", Level: ErrorLevelCritical})
+		} else if info, err := os.Stat(r.Files.Directory); err != nil || !info.IsDir() {
+			result = append(result, RouteError{Message: fmt.Sprintf("files directory '%s' does not exist", r.Files.Directory), Level: ErrorLevelWarning})
+		}
+		if r.Files.Index == "" {
+			r.Files.Index = "index.html"
+		}
+		if !strings.HasSuffix(r.Route, "/*") {
+			result = append(result, RouteError{Message: "route does not end with '/*' and only serves the directory itself", Level: ErrorLevelInfo})
+		}
+		if r.Method != http.MethodGet {
+			result = append(result, RouteError{Message: "files should be served with GET", Level: ErrorLevelWarning})
+		}
+	case "proxy":
+		if upstream, err := url.Parse(r.Proxy.Upstream); err != nil || (upstream.Scheme != "http" && upstream.Scheme != "https") || upstream.Host == "" {
+			result = append(result, RouteError{Message: fmt.Sprintf("proxy upstream '%s' must be an absolute http(s) url", r.Proxy.Upstream), Level: ErrorLevelCritical})
+		}
+	}
+
+	return
+}
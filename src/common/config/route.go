@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"net/http"
 	"slices"
 	"strings"
@@ -8,7 +9,7 @@ import (
 
 const RouteParamPrefix = "WC_"
 
-var allowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+var allowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions}
 
 type Route struct {
 	Method         string            // HTTP method
@@ -18,17 +19,72 @@ type Route struct {
 	StatusCodes    []ExitCodeMapping // List of exit-code to status-code mappings
 	AllowBody      bool              // Enable reading the request body and writing it into stdin of the exec environment
 	Exec           RouteExec         // Exec config
+	Static         *RouteStatic      // Respond with a fixed body instead of executing
+	Redirect       *RouteRedirect    // Redirect to another location instead of executing
+	Files          *RouteFiles       // Serve files of a directory instead of executing
+	Proxy          *RouteProxy       // Forward requests to an upstream server instead of executing
 	ResponseStream StdStream         // Default output stream used in response for all exit codes
 	Caching        bool              // Enable caching for this route. Is disabled by default.
+	ErrorFormat    ErrorFormat       // Format of error responses. Plain by default, "problem" for RFC 9457 problem details
+	CORS           *CORSConfig       // CORS config replacing the server-wide config. Disables CORS for this route if it has no origins
+	Auth           []string          // Names of the authenticators of which one has to accept the request. No authentication by default
+	RequireScopes  []string          // Scopes the authenticated principal needs, from its "scope" or "scp" claim
+	RequireClaims  map[string]string // Claims the authenticated principal needs with the given value. Array claims have to contain it
+	RateLimit      *RateLimit        // Rate limit of this route, in addition to the global rate limit
+	AllowFrom      []string          // CIDR ranges of clients allowed to access this route, in addition to the global lists. All clients by default
+	DenyFrom       []string          // CIDR ranges of clients denied to access this route
+	Webhook        *RouteWebhook     // Verify the signature of webhook deliveries before executing
+	HealthCheck    *HealthCheck      // Command run periodically, which has to succeed for readiness
+	Summary        string            // Short summary of the route in the OpenAPI document
+	Description    string            // Description of the route in the OpenAPI document
+	Tags           []string          // Tags grouping the route in the OpenAPI document
+	Listeners      []string          // Names of the listeners serving this route. All listeners by default
 }
 
+// Maps the result of an execution to a response. All defined conditions (exit code, range, signal and output patterns) have to match.
+// A mapping without any condition is the default for results that are not matched otherwise.
 type ExitCodeMapping struct {
 	ExitCode       *int              // The base exit code from which to map from
+	ExitCodes      *ExitCodeRange    // Inclusive range of exit codes to map from (like "10-19")
+	Signal         string            // Name of the signal that terminated the process (like SIGKILL)
+	Stdout         string            // Regex that has to match the process' stdout
+	Stderr         string            // Regex that has to match the process' stderr
 	StatusCode     int               // Status code to map to
 	Headers        map[string]string // Special response headers for this exit code
 	ResponseStream StdStream         // Output stream used in response for this exit code
 }
 
+// Returns true if the mapping has no conditions and thus is a default mapping
+func (m *ExitCodeMapping) IsDefault() bool {
+	return m.ExitCode == nil && m.ExitCodes == nil && m.Signal == "" && m.Stdout == "" && m.Stderr == ""
+}
+
+// Prints the conditions of this mapping (example: exit code 1, signal SIGKILL)
+func (m *ExitCodeMapping) String() string {
+	if m.IsDefault() {
+		return "default mapping"
+	}
+
+	var conditions []string
+	if m.ExitCode != nil {
+		conditions = append(conditions, fmt.Sprintf("exit code %v", *m.ExitCode))
+	}
+	if m.ExitCodes != nil {
+		conditions = append(conditions, fmt.Sprintf("exit codes %s", m.ExitCodes))
+	}
+	if m.Signal != "" {
+		conditions = append(conditions, "signal "+m.Signal)
+	}
+	if m.Stdout != "" {
+		conditions = append(conditions, fmt.Sprintf("stdout '%s'", m.Stdout))
+	}
+	if m.Stderr != "" {
+		conditions = append(conditions, fmt.Sprintf("stderr '%s'", m.Stderr))
+	}
+
+	return strings.Join(conditions, ", ")
+}
+
 // Stream constants
 type StdStream string
 
@@ -45,6 +101,16 @@ func (stream StdStream) IsValid() bool {
 	return stream == "" || slices.Contains(allowedStreams, StdStream(strings.ToLower(string(stream))))
 }
 
+// Error format constants
+type ErrorFormat string
+
+const (
+	ErrorFormatPlain   ErrorFormat = "plain"
+	ErrorFormatProblem ErrorFormat = "problem"
+)
+
+var allowedErrorFormats = []ErrorFormat{"", ErrorFormatPlain, ErrorFormatProblem}
+
 // Return a default route configuration that can be used as the base for further configuration.
 func DefaultRoute() Route {
 	var zero int = 0
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+)
+
+// Perform check on the server config and return a collection of remarks
+func CheckServer(c *server.Config) (result RouteErrorCollection) {
+	if c.TLS != nil {
+		for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+			if file == "" {
+				result = append(result, RouteError{Message: "tls requires 'certFile' and 'keyFile'", Level: ErrorLevelCritical})
+				break
+			} else if _, err := os.Stat(file); err != nil {
+				result = append(result, RouteError{Message: fmt.Sprintf("tls file '%s' can not be read", file), Level: ErrorLevelCritical})
+			}
+		}
+
+		if c.TLS.MinVersion == "" {
+			c.TLS.MinVersion = server.TLSVersion12
+		} else if !slices.Contains([]string{server.TLSVersion12, server.TLSVersion13}, c.TLS.MinVersion) {
+			result = append(result, RouteError{Message: fmt.Sprintf("tls min version '%s' is not supported, use '1.2' or '1.3'", c.TLS.MinVersion), Level: ErrorLevelCritical})
+		}
+
+		if c.TLS.CipherPolicy == "" {
+			c.TLS.CipherPolicy = server.CipherPolicyDefault
+		} else if !slices.Contains([]string{server.CipherPolicyDefault, server.CipherPolicyModern}, c.TLS.CipherPolicy) {
+			result = append(result, RouteError{Message: fmt.Sprintf("tls cipher policy '%s' is not supported, use 'default' or 'modern'", c.TLS.CipherPolicy), Level: ErrorLevelCritical})
+		}
+	}
+
+	if c.ClientAuth != nil {
+		if c.TLS == nil {
+			result = append(result, RouteError{Message: "client auth requires 'tls'", Level: ErrorLevelCritical})
+		}
+		if c.ClientAuth.CAFile == "" {
+			result = append(result, RouteError{Message: "client auth requires 'caFile'", Level: ErrorLevelCritical})
+		} else if _, err := os.Stat(c.ClientAuth.CAFile); err != nil {
+			result = append(result, RouteError{Message: fmt.Sprintf("client CA file '%s' can not be read", c.ClientAuth.CAFile), Level: ErrorLevelCritical})
+		}
+		if c.ClientAuth.Mode == "" {
+			c.ClientAuth.Mode = server.ClientAuthRequired
+		} else if !slices.Contains([]string{server.ClientAuthRequired, server.ClientAuthOptional}, c.ClientAuth.Mode) {
+			result = append(result, RouteError{Message: fmt.Sprintf("client auth mode '%s' is not supported, use 'required' or 'optional'", c.ClientAuth.Mode), Level: ErrorLevelCritical})
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value timem.Duration
+	}{
+		{"drain timeout", c.DrainTimeout},
+		{"read header timeout", c.ReadHeaderTimeout},
+		{"read timeout", c.ReadTimeout},
+		{"write timeout", c.WriteTimeout},
+		{"idle timeout", c.IdleTimeout},
+	}
+	for _, duration := range durations {
+		if duration.value < 0 {
+			result = append(result, RouteError{Message: duration.name + " must not be negative", Level: ErrorLevelCritical})
+		}
+	}
+	if c.MaxHeaderBytes < 0 {
+		result = append(result, RouteError{Message: "max header bytes must not be negative", Level: ErrorLevelCritical})
+	}
+	if c.MaxBodyBytes < 0 {
+		result = append(result, RouteError{Message: "max body bytes must not be negative", Level: ErrorLevelCritical})
+	}
+
+	if c.ReadHeaderTimeout == 0 && c.ReadTimeout == 0 {
+		result = append(result, RouteError{Message: "no read header timeout or read timeout set: slow clients can hold connections open indefinitely", Level: ErrorLevelWarning})
+	} else if c.ReadTimeout > 0 && c.ReadHeaderTimeout > c.ReadTimeout {
+		result = append(result, RouteError{Message: "read header timeout is longer than the read timeout and has no effect", Level: ErrorLevelWarning})
+	}
+	if c.WriteTimeout > 0 {
+		result = append(result, RouteError{Message: fmt.Sprintf("write timeout of %v also limits the execution time of commands", time.Duration(c.WriteTimeout)), Level: ErrorLevelInfo})
+	}
+	result = append(result, checkListeners(c)...)
+
+	if c.H2C && c.TLS != nil {
+		result = append(result, RouteError{Message: "h2c has no effect with tls, HTTP/2 is negotiated over TLS", Level: ErrorLevelWarning})
+	}
+
+	return
+}
+
+// Check the listeners and default their type and name
+func checkListeners(c *server.Config) (result RouteErrorCollection) {
+	if len(c.Listeners) > 0 && c.Socket != "" {
+		result = append(result, RouteError{Message: "socket is ignored, as listeners are configured", Level: ErrorLevelWarning})
+	}
+
+	names := make(map[string]bool)
+	for i := range c.Listeners {
+		l := &c.Listeners[i]
+		if l.Type == "" {
+			l.Type = server.ListenerTCP
+		}
+
+		switch l.Type {
+		case server.ListenerTCP:
+			if l.Port == 0 {
+				result = append(result, RouteError{Message: "tcp listener requires 'port'", Level: ErrorLevelCritical})
+			}
+		case server.ListenerUnix:
+			if l.Path == "" {
+				result = append(result, RouteError{Message: "unix listener requires 'path'", Level: ErrorLevelCritical})
+			}
+			if l.Mode == "" {
+				l.Mode = server.DefaultSocketMode
+			} else if _, err := strconv.ParseUint(l.Mode, 8, 32); err != nil {
+				result = append(result, RouteError{Message: fmt.Sprintf("socket mode '%s' is no octal file mode (like 0660)", l.Mode), Level: ErrorLevelCritical})
+			}
+			if l.Owner != "" {
+				if runtime.GOOS == "windows" {
+					result = append(result, RouteError{Message: "socket owner is not supported on windows", Level: ErrorLevelCritical})
+				} else if _, _, err := server.LookupOwner(l.Owner); err != nil {
+					result = append(result, RouteError{Message: fmt.Sprintf("socket owner '%s' is unknown: %s", l.Owner, err.Error()), Level: ErrorLevelCritical})
+				}
+			}
+		case server.ListenerSystemd:
+			if runtime.GOOS == "windows" {
+				result = append(result, RouteError{Message: "systemd listeners are not supported on windows", Level: ErrorLevelCritical})
+			} else if !server.SocketActivated() {
+				result = append(result, RouteError{Message: "systemd listener requires socket activation, but no sockets were passed", Level: ErrorLevelWarning})
+			}
+		default:
+			result = append(result, RouteError{Message: fmt.Sprintf("listener type '%s' is not supported, use 'tcp', 'unix' or 'systemd'", l.Type), Level: ErrorLevelCritical})
+		}
+		if l.TrustForwarded && l.Type == server.ListenerTCP {
+			result = append(result, RouteError{Message: "all clients of a tcp listener trusting forwarded addresses can forge their address, list the proxies in 'trustedProxies' instead", Level: ErrorLevelWarning})
+		}
+		if l.Type != server.ListenerTCP && (l.Host != "" || l.Port != 0) {
+			result = append(result, RouteError{Message: fmt.Sprintf("host and port are ignored for %s listeners", l.Type), Level: ErrorLevelWarning})
+		}
+
+		if l.Name == "" {
+			l.Name = l.Address()
+		}
+		if names[l.Name] {
+			result = append(result, RouteError{Message: fmt.Sprintf("listener name '%s' is not unique", l.Name), Level: ErrorLevelCritical})
+		}
+		names[l.Name] = true
+	}
+
+	return
+}
+
+// Check that all listeners referenced by the route are defined
+func (r *Route) CheckListeners(listeners []server.ListenerConfig) (result RouteErrorCollection) {
+	for _, name := range r.Listeners {
+		if !slices.ContainsFunc(listeners, func(l server.ListenerConfig) bool { return l.Name == name }) {
+			result = append(result, RouteError{Message: fmt.Sprintf("listener '%s' is not defined in the server config", name), Level: ErrorLevelCritical})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Export of trace spans over OTLP/HTTP
+type TracingConfig struct {
+	Endpoint    string            // Base URL of the OTLP/HTTP collector (like http://localhost:4318). Spans are sent to its /v1/traces path
+	ServiceName string            // Value of the service.name resource attribute. "webcmd" by default
+	Headers     map[string]string // Additional headers of export requests, like authentication
+}
+
+// Perform check on all fields and return a collection of remarks
+func (t *TracingConfig) Check() (result RouteErrorCollection) {
+	if t == nil {
+		return
+	}
+
+	if t.Endpoint == "" {
+		result = append(result, RouteError{Message: "tracing requires 'endpoint'", Level: ErrorLevelCritical})
+	} else if endpoint, err := url.Parse(t.Endpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		result = append(result, RouteError{Message: fmt.Sprintf("tracing endpoint '%s' is not a valid http or https url", t.Endpoint), Level: ErrorLevelCritical})
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = "webcmd"
+	}
+
+	return
+}
+
+// Full URL spans are exported to
+func (t *TracingConfig) TracesURL() string {
+	if strings.HasSuffix(t.Endpoint, "/v1/traces") {
+		return t.Endpoint
+	}
+	return strings.TrimSuffix(t.Endpoint, "/") + "/v1/traces"
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+)
+
+// Verification of webhook deliveries, before the route is executed
+type RouteWebhook struct {
+	Provider       WebhookProvider // Sender of the webhook, defining how deliveries are signed. Generic by default
+	SecretFile     string          // File containing the shared secret
+	SecretEnv      string          // Env variable containing the shared secret
+	Header         string          // Generic only: header containing the hex encoded HMAC signature of the body
+	Algorithm      string          // Generic only: hash algorithm of the HMAC (sha1, sha256 or sha512). sha256 by default
+	Prefix         string          // Generic only: prefix of the signature in the header (like "sha256=")
+	RejectReplays  bool            // Reject deliveries whose delivery ID was already seen. The ID is not signed, so this only detects redeliveries
+	DeliveryHeader string          // Header containing the delivery ID. Set by default for GitHub and GitLab
+
+	TimestampHeader string         // Generic only: header with the unix time of the delivery, which is signed as "<timestamp>.<body>"
+	Tolerance       timem.Duration // Generic only: maximum difference of the signed timestamp to the current time. 5 minutes by default
+}
+
+const defaultWebhookTolerance = timem.Duration(5 * time.Minute)
+
+type WebhookProvider string
+
+const (
+	WebhookGitHub  WebhookProvider = "github"  // HMAC-SHA256 signature of the body in X-Hub-Signature-256
+	WebhookGitLab  WebhookProvider = "gitlab"  // Secret token in X-Gitlab-Token
+	WebhookGeneric WebhookProvider = "generic" // HMAC signature of the body in a configurable header
+)
+
+var allowedWebhookProviders = []WebhookProvider{WebhookGitHub, WebhookGitLab, WebhookGeneric}
+
+var allowedWebhookAlgorithms = []string{"sha1", "sha256", "sha512"}
+
+// Perform check on all fields and return a collection of remarks
+func (w *RouteWebhook) Check() (result RouteErrorCollection) {
+	if w == nil {
+		return
+	}
+
+	if w.Provider == "" {
+		w.Provider = WebhookGeneric
+	} else if !slices.Contains(allowedWebhookProviders, w.Provider) {
+		result = append(result, RouteError{Message: fmt.Sprintf("invalid webhook provider '%s'", w.Provider), Level: ErrorLevelCritical})
+	}
+
+	if (w.SecretFile == "") == (w.SecretEnv == "") {
+		result = append(result, RouteError{Message: "webhook requires either 'secretFile' or 'secretEnv'", Level: ErrorLevelCritical})
+	} else if w.SecretFile != "" {
+		if _, err := os.Stat(w.SecretFile); err != nil {
+			result = append(result, RouteError{Message: fmt.Sprintf("webhook secret file '%s' can not be read", w.SecretFile), Level: ErrorLevelCritical})
+		}
+	} else if os.Getenv(w.SecretEnv) == "" {
+		result = append(result, RouteError{Message: fmt.Sprintf("webhook secret env variable '%s' is not set", w.SecretEnv), Level: ErrorLevelCritical})
+	}
+
+	if w.Provider == WebhookGeneric {
+		if w.Header == "" {
+			result = append(result, RouteError{Message: "generic webhook requires 'header'", Level: ErrorLevelCritical})
+		}
+		if w.Algorithm == "" {
+			w.Algorithm = "sha256"
+		} else if !slices.Contains(allowedWebhookAlgorithms, w.Algorithm) {
+			result = append(result, RouteError{Message: fmt.Sprintf("invalid webhook algorithm '%s', use 'sha1', 'sha256' or 'sha512'", w.Algorithm), Level: ErrorLevelCritical})
+		}
+		if w.TimestampHeader != "" && w.Tolerance == 0 {
+			w.Tolerance = defaultWebhookTolerance
+		} else if w.Tolerance < 0 {
+			result = append(result, RouteError{Message: "webhook tolerance must not be negative", Level: ErrorLevelCritical})
+		}
+	} else if w.Header != "" || w.Algorithm != "" || w.Prefix != "" || w.TimestampHeader != "" {
+		result = append(result, RouteError{Message: fmt.Sprintf("webhook 'header', 'algorithm', 'prefix' and 'timestampHeader' are ignored for provider '%s'", w.Provider), Level: ErrorLevelWarning})
+	}
+
+	if w.DeliveryHeader == "" {
+		switch w.Provider {
+		case WebhookGitHub:
+			w.DeliveryHeader = "X-GitHub-Delivery"
+		case WebhookGitLab:
+			w.DeliveryHeader = "X-Gitlab-Event-UUID"
+		}
+	}
+	if w.RejectReplays && w.DeliveryHeader == "" && !w.SignsTimestamp() {
+		result = append(result, RouteError{Message: "rejecting webhook replays requires 'deliveryHeader' or 'timestampHeader'", Level: ErrorLevelCritical})
+	}
+
+	return
+}
+
+// Returns true if the timestamp of deliveries is signed, which protects them against replays
+func (w *RouteWebhook) SignsTimestamp() bool {
+	return w.Provider == WebhookGeneric && w.TimestampHeader != ""
+}
@@ -12,14 +12,18 @@ import (
 type ExecMode string
 
 const (
-	ModeProc  ExecMode = "proc"
-	ModeShell ExecMode = "shell"
+	ModeProc     ExecMode = "proc"
+	ModeShell    ExecMode = "shell"
+	ModeFastCGI  ExecMode = "fastcgi"
+	ModeSSH      ExecMode = "ssh"
+	ModePipeline ExecMode = "pipeline"
 )
 
 // A collection of executers for different exec modes. Ready to use.
 // Enables to pick the right executer for a route.
 type ExecuterCollection struct {
 	executers map[ExecMode]Executer
+	running   *Tracker
 }
 
 // Add an executer to the collection
@@ -46,19 +50,37 @@ func (c *ExecuterCollection) SetOnly(executer Executer, onlyOS ...string) {
 	}
 }
 
+// Tracker of the running executions of all executers in this collection
+func (c *ExecuterCollection) Running() *Tracker {
+	if c.running == nil {
+		c.running = NewTracker()
+	}
+	return c.running
+}
+
 // Retrieve list of all registered executers in this collection
 func (c *ExecuterCollection) Available() []Executer {
 	return slices.AppendSeq([]Executer{}, maps.Values(c.executers))
 }
 
-// Retrieve the right executer for a route
+// Retrieve the right executer for a route. Returns nil without error for routes without exec config
 func (c *ExecuterCollection) For(route *config.Route) (Executer, error) {
+	if len(route.Exec.Modes()) == 0 {
+		return nil, nil
+	}
+
 	var mode ExecMode = ""
 	switch {
 	case route.Exec.Proc != nil:
 		mode = ModeProc
 	case route.Exec.Shell != nil:
 		mode = ModeShell
+	case route.Exec.FastCGI != nil:
+		mode = ModeFastCGI
+	case route.Exec.SSH != nil:
+		mode = ModeSSH
+	case len(route.Exec.Pipeline) > 0:
+		mode = ModePipeline
 	}
 
 	executer, ok := c.executers[mode]
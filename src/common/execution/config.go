@@ -2,6 +2,8 @@ package execution
 
 import (
 	"io"
+	"net/http"
+	"os"
 
 	"github.com/bdoerfchen/webcmd/src/common/config"
 )
@@ -11,6 +13,16 @@ type Config struct {
 	Args    []string          // Process args
 	Env     map[string]string // Raw environment variable map
 	Stdin   io.Reader         // Stdin stream. Can be nil to use /dev/null
+	Exec    config.RouteExec  // The route's exec config, for executers requiring mode specific settings
+	Request *http.Request     // The incoming request. Can be used by executers to forward request metadata
+	Started func(*os.Process) // Called by executers with each started local process. Can be nil
+}
+
+// Report a started local process, if the caller is interested in it
+func (c *Config) ReportStarted(process *os.Process) {
+	if c.Started != nil && process != nil {
+		c.Started(process)
+	}
 }
 
 func ConfigFromRoute(route *config.Route) Config {
@@ -18,6 +30,7 @@ func ConfigFromRoute(route *config.Route) Config {
 	execConfig := Config{
 		Env:   make(map[string]string),
 		Stdin: nil,
+		Exec:  route.Exec,
 	}
 
 	switch {
@@ -26,6 +39,14 @@ func ConfigFromRoute(route *config.Route) Config {
 		execConfig.Args = route.Exec.Proc.Args
 	case route.Exec.Shell != nil:
 		execConfig.Command = route.Exec.Shell.Command
+	case route.Exec.FastCGI != nil:
+		execConfig.Command = route.Exec.FastCGI.Script
+	case route.Exec.SSH != nil:
+		execConfig.Command = route.Exec.SSH.Command
+	case len(route.Exec.Pipeline) > 0:
+		// First step, all steps are read from Exec by the executer
+		execConfig.Command = route.Exec.Pipeline[0].Path
+		execConfig.Args = route.Exec.Pipeline[0].Args
 	default:
 		// Should not be called, as the app detects this case on config check and exits
 		panic("missing exec config")
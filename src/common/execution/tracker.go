@@ -0,0 +1,142 @@
+package execution
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/process"
+)
+
+var ErrNoLocalProcess = errors.New("execution has no local process")
+
+// Tracks the running executions, so they can be listed and terminated
+type Tracker struct {
+	mutex   sync.Mutex
+	lastID  uint64
+	running map[uint64]*Running
+}
+
+// A running execution
+type Running struct {
+	id        uint64
+	route     string
+	requestID string
+	started   time.Time
+	processes []*os.Process
+}
+
+// Snapshot of a running execution
+type Execution struct {
+	ID        uint64
+	Route     string
+	RequestID string
+	Started   time.Time
+	PIDs      []int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{running: make(map[uint64]*Running)}
+}
+
+// Track a starting execution of the route until Finish is called
+func (t *Tracker) Start(route, requestID string) *Running {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.lastID++
+	running := &Running{id: t.lastID, route: route, requestID: requestID, started: time.Now()}
+	t.running[running.id] = running
+	return running
+}
+
+// Add a local process started by the execution. Its process group is signalled on termination
+func (t *Tracker) AddProcess(running *Running, process *os.Process) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	running.processes = append(running.processes, process)
+}
+
+func (t *Tracker) Finish(running *Running) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.running, running.id)
+}
+
+// Number of running executions
+func (t *Tracker) Count() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.running)
+}
+
+// List the running executions, ordered by their start
+func (t *Tracker) List() []Execution {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	result := make([]Execution, 0, len(t.running))
+	for _, id := range slices.Sorted(maps.Keys(t.running)) {
+		running := t.running[id]
+		execution := Execution{ID: id, Route: running.route, RequestID: running.requestID, Started: running.started, PIDs: []int{}}
+		for _, process := range running.processes {
+			execution.PIDs = append(execution.PIDs, process.Pid)
+		}
+		result = append(result, execution)
+	}
+
+	return result
+}
+
+// Kill the process groups of a running execution. Returns false if there is no such execution
+func (t *Tracker) Kill(id uint64) (bool, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	running, ok := t.running[id]
+	if !ok {
+		return false, nil
+	}
+	if len(running.processes) == 0 {
+		return true, ErrNoLocalProcess
+	}
+	return true, running.signal(syscall.SIGKILL)
+}
+
+// Send SIGTERM to the process groups of all running executions, and SIGKILL to those still running after the grace period.
+// Returns the number of executions that had to be killed
+func (t *Tracker) Terminate(grace time.Duration) int {
+	t.signalAll(syscall.SIGTERM)
+
+	deadline := time.Now().Add(grace)
+	for t.Count() > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return t.signalAll(syscall.SIGKILL)
+}
+
+// Signal all running executions and return their number
+func (t *Tracker) signalAll(signal syscall.Signal) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, running := range t.running {
+		running.signal(signal)
+	}
+	return len(t.running)
+}
+
+func (r *Running) signal(signal syscall.Signal) error {
+	var errs []error
+	for _, p := range r.processes {
+		if err := process.SignalGroup(p, signal); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,10 @@
+package health
+
+type Monitor interface {
+	// Add a check that is evaluated on every readiness request. It returns an error while not ready
+	AddCheck(name string, check func() error)
+	// Remove the checks with the name
+	RemoveCheck(name string)
+	// Returns the error of each failing check by its name. Empty if ready
+	Failures() map[string]string
+}
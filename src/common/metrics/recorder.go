@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+type Recorder interface {
+	// Count a finished request of the route pattern
+	ObserveRequest(route, method string, status int, duration time.Duration)
+	// Count an execution as in flight until it is finished
+	ExecutionStarted()
+	// Count a finished execution of the route pattern. The exit code is "error" if the command could not be executed
+	ExecutionFinished(route, exitCode string, duration time.Duration)
+	// Count a request of a cached route, which was either answered from cache or not
+	ObserveCache(hit bool)
+	// Add a gauge whose value is read on every scrape
+	AddGauge(name, help string, value func() float64)
+	// Handler serving the metrics
+	Handler() http.Handler
+}
+
+// A recorder that drops all metrics
+var Discard Recorder = discard{}
+
+type discard struct{}
+
+func (discard) ObserveRequest(string, string, int, time.Duration) {}
+func (discard) ExecutionStarted()                                 {}
+func (discard) ExecutionFinished(string, string, time.Duration)   {}
+func (discard) ObserveCache(bool)                                 {}
+func (discard) AddGauge(string, string, func() float64)           {}
+func (discard) Handler() http.Handler                             { return http.NotFoundHandler() }
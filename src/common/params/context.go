@@ -0,0 +1,24 @@
+package params
+
+import (
+	"context"
+	"maps"
+)
+
+type envKey struct{}
+
+// Add env variables to the context, which are merged into the exec environment of the request. Existing variables with the same name are overwritten
+func AddEnvToContext(ctx context.Context, env EnvMap) context.Context {
+	merged := maps.Clone(EnvFromContext(ctx))
+	if merged == nil {
+		merged = make(EnvMap, len(env))
+	}
+	maps.Copy(merged, env)
+	return context.WithValue(ctx, envKey{}, merged)
+}
+
+// Get the env variables added to the context. Returns nil if there are none
+func EnvFromContext(ctx context.Context) EnvMap {
+	env, _ := ctx.Value(envKey{}).(EnvMap)
+	return env
+}
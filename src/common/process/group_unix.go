@@ -0,0 +1,27 @@
+//go:build !windows
+
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Start the command in its own process group, so it can be signalled together with its children
+func SetGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// Send the signal to the process group led by the process. Returns os.ErrProcessDone if the group has no processes left
+func SignalGroup(process *os.Process, signal syscall.Signal) error {
+	err := syscall.Kill(-process.Pid, signal)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
@@ -0,0 +1,15 @@
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Process groups are not used on windows
+func SetGroup(cmd *exec.Cmd) {}
+
+// Windows processes can not be signalled, so the process is killed regardless of the signal
+func SignalGroup(process *os.Process, signal syscall.Signal) error {
+	return process.Kill()
+}
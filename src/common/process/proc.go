@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/http"
 	"os/exec"
+	"sync"
 )
 
 type Process struct {
@@ -13,6 +15,10 @@ type Process struct {
 	StdErr    bytes.Buffer
 	StdOutErr bytes.Buffer
 	Proc      *exec.Cmd
+
+	Header     http.Header // Response headers reported by the executed application. Can be nil
+	StatusCode int         // Response status code reported by the executed application. Zero if the exit code mapping should decide
+	Signal     string      // Name of the signal that terminated the process (e.g. SIGKILL). Empty if it exited normally
 }
 
 // Creates a new process reference with connected streams, but is not started yet
@@ -20,6 +26,7 @@ func Prepare(template *Template) (*Process, error) {
 	result := &Process{}
 
 	result.Proc = exec.Command(template.Command, template.Args...)
+	SetGroup(result.Proc)
 	if template.OpenStdIn {
 		in, err := result.Proc.StdinPipe()
 		if err != nil {
@@ -29,8 +36,26 @@ func Prepare(template *Template) (*Process, error) {
 	}
 
 	// Connect stdout and stderr (+ multi buffer)
-	result.Proc.Stdout = io.MultiWriter(&result.StdOut, &result.StdOutErr)
-	result.Proc.Stderr = io.MultiWriter(&result.StdErr, &result.StdOutErr)
+	result.Proc.Stdout, result.Proc.Stderr = result.Writers()
 
 	return result, nil
 }
+
+// Returns writers for stdout and stderr, both also writing into the combined buffer. Safe to be written to concurrently
+func (p *Process) Writers() (stdout io.Writer, stderr io.Writer) {
+	mutex := &sync.Mutex{}
+	return &lockedWriter{mutex: mutex, writer: io.MultiWriter(&p.StdOut, &p.StdOutErr)},
+		&lockedWriter{mutex: mutex, writer: io.MultiWriter(&p.StdErr, &p.StdOutErr)}
+}
+
+// A writer that serializes writes to the underlying writer with a mutex that can be shared
+type lockedWriter struct {
+	mutex  *sync.Mutex
+	writer io.Writer
+}
+
+func (w *lockedWriter) Write(b []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.writer.Write(b)
+}
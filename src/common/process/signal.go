@@ -0,0 +1,64 @@
+package process
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// Signals that are available on all platforms, by their name
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGILL:  "SIGILL",
+	syscall.SIGTRAP: "SIGTRAP",
+	syscall.SIGABRT: "SIGABRT",
+	syscall.SIGBUS:  "SIGBUS",
+	syscall.SIGFPE:  "SIGFPE",
+	syscall.SIGKILL: "SIGKILL",
+	syscall.SIGSEGV: "SIGSEGV",
+	syscall.SIGPIPE: "SIGPIPE",
+	syscall.SIGALRM: "SIGALRM",
+	syscall.SIGTERM: "SIGTERM",
+}
+
+// Returns the name of the signal that terminated the finished command (e.g. SIGKILL), or an empty string if it exited normally
+func SignalOf(cmd *exec.Cmd) string {
+	if cmd == nil || cmd.ProcessState == nil {
+		return ""
+	}
+
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+
+	if name, ok := signalNames[status.Signal()]; ok {
+		return name
+	}
+	return fmt.Sprintf("SIG%d", int(status.Signal()))
+}
+
+// Normalizes a signal name to its upper case form with SIG prefix (kill -> SIGKILL)
+func NormalizeSignalName(name string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name != "" && !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+
+	return name
+}
+
+// Returns true if the signal name is known on all platforms
+func IsKnownSignal(name string) bool {
+	name = NormalizeSignalName(name)
+	for _, known := range signalNames {
+		if known == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,19 @@
+package ratelimit
+
+import "time"
+
+// The outcome of a rate limited request
+type Result struct {
+	Allowed    bool          // True if the request may proceed
+	Limit      int           // Maximum number of requests at once
+	Remaining  int           // Requests that may follow immediately
+	Reset      time.Duration // Time until the limit is fully restored
+	RetryAfter time.Duration // Time until the next request is allowed. Zero if allowed
+}
+
+type Limiter interface {
+	// Take a request of the key into account and return whether it is allowed
+	Allow(key string) Result
+	// Stop the background work of the limiter. Requests may still be allowed afterwards
+	Stop()
+}
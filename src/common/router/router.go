@@ -2,12 +2,19 @@ package router
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/bdoerfchen/webcmd/src/common/config"
 )
 
 type Router interface {
-	Register(ctx context.Context, routes []config.Route) error
+	Register(ctx context.Context, routes []config.Route, groups []config.RouteGroup) error
 	Handler() http.Handler
+	// Handler of the admin API, protected by the named authenticators
+	AdminHandler(authenticators []string, logger *slog.Logger) (http.Handler, error)
+	// Take over the state of the routes of the router this one replaces, like maintenance, received webhook deliveries and cached responses of unchanged routes
+	Inherit(previous Router)
+	// Stop the background work of the router. Requests in flight may still be served
+	Close()
 }
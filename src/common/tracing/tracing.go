@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Env variables exposing the trace context of the execution to commands
+const (
+	EnvTraceparent = "TRACEPARENT"
+	EnvTracestate  = "TRACESTATE"
+)
+
+// Identifies a span within a trace, as propagated with the W3C traceparent and tracestate headers
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+	State   string // Vendor specific tracestate, passed on unchanged
+}
+
+// Returns true if trace and span ID are set
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != [16]byte{} && c.SpanID != [8]byte{}
+}
+
+// Format as traceparent header value (like 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01)
+func (c SpanContext) Traceparent() string {
+	flags := "00"
+	if c.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(c.TraceID[:]), hex.EncodeToString(c.SpanID[:]), flags)
+}
+
+// Parse the traceparent and tracestate header values. Returns false if the traceparent is invalid
+func ParseTraceparent(traceparent, tracestate string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	var result SpanContext
+	var flags [1]byte
+	for _, field := range []struct {
+		value string
+		dst   []byte
+	}{{parts[1], result.TraceID[:]}, {parts[2], result.SpanID[:]}, {parts[3], flags[:]}} {
+		// Only lowercase hex is valid
+		if len(field.value) != 2*len(field.dst) || strings.ToLower(field.value) != field.value {
+			return SpanContext{}, false
+		}
+		if _, err := hex.Decode(field.dst, []byte(field.value)); err != nil {
+			return SpanContext{}, false
+		}
+	}
+	if !result.IsValid() {
+		return SpanContext{}, false
+	}
+
+	result.Sampled = flags[0]&1 == 1
+	result.State = strings.TrimSpace(tracestate)
+	return result, true
+}
+
+// Span kinds, with the values of OpenTelemetry
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+type Span interface {
+	// The context of this span, used to propagate it
+	Context() SpanContext
+	// Rename the span, for names that are only known when it ends
+	SetName(name string)
+	// Add an attribute. Values are strings, integers, floats or booleans
+	SetAttribute(key string, value any)
+	// Mark the span as failed
+	SetError(message string)
+	// Finish the span and hand it over for export
+	End()
+	// Start a child span of this span
+	StartChild(name string, kind SpanKind) Span
+}
+
+type Tracer interface {
+	// Start a root span, or the local child of a remote parent if it is valid
+	Start(name string, kind SpanKind, parent SpanContext) Span
+}
+
+type spanKey struct{}
+
+// Add the span to the context, so child spans can be started from it
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// Returns the span of the context, or a span that records nothing
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// Start a child of the span in the context, and return a context with the child
+func Start(ctx context.Context, name string) (context.Context, Span) {
+	span := SpanFromContext(ctx).StartChild(name, KindInternal)
+	return ContextWithSpan(ctx, span), span
+}
+
+// A tracer that records nothing, but still propagates the trace context of remote parents
+var Discard Tracer = discard{}
+
+type discard struct{}
+
+func (discard) Start(name string, kind SpanKind, parent SpanContext) Span {
+	return noopSpan{context: parent}
+}
+
+type noopSpan struct {
+	context SpanContext
+}
+
+func (s noopSpan) Context() SpanContext                       { return s.context }
+func (noopSpan) SetName(string)                               {}
+func (noopSpan) SetAttribute(string, any)                     {}
+func (noopSpan) SetError(string)                              {}
+func (noopSpan) End()                                         {}
+func (s noopSpan) StartChild(name string, kind SpanKind) Span { return s }
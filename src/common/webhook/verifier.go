@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrMissingDelivery  = errors.New("missing delivery id")
+	ErrReplayed         = errors.New("delivery was already received")
+	ErrExpired          = errors.New("delivery timestamp is outside the tolerance")
+)
+
+type Verifier interface {
+	// Verify that the delivery was sent by the owner of the secret, and was not received before
+	Verify(header http.Header, body []byte) error
+	// Take over the deliveries received by the previous verifier of the route, so replays are still detected
+	Inherit(previous Verifier)
+}
@@ -25,6 +25,14 @@ func FromContext(ctx context.Context) *slog.Logger {
 	return New(slog.LevelDebug, false).With(slog.String("logger", "tmp"))
 }
 
+// Get logger from context, or the fallback if the context has none
+func FromContextOr(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(contextLoggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
 func AddToContext(ctx context.Context, logger *slog.Logger) context.Context {
 	return context.WithValue(ctx, contextLoggerKey, logger)
 }
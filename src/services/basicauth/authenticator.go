@@ -0,0 +1,88 @@
+package basicauth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Compared against for unknown users, so they take as long as known users with a wrong password
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("webcmd"), bcrypt.DefaultCost)
+	return hash
+})
+
+// An authenticator checking basic auth credentials against the bcrypt hashes of an htpasswd file
+type basicAuthenticator struct {
+	users     map[string][]byte // Password hashes by user name
+	challenge string
+}
+
+func New(config *config.AuthBasic) (*basicAuthenticator, error) {
+	content, err := os.ReadFile(config.HtpasswdFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read htpasswd file: %w", err)
+	}
+
+	users, err := parseHtpasswd(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid htpasswd file %s: %w", config.HtpasswdFile, err)
+	}
+
+	return &basicAuthenticator{
+		users:     users,
+		challenge: fmt.Sprintf(`Basic realm="%s", charset="UTF-8"`, config.Realm),
+	}, nil
+}
+
+// Read the "user:hash" lines of an htpasswd file
+func parseHtpasswd(content []byte) (map[string][]byte, error) {
+	users := make(map[string][]byte)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		user, hash, ok := strings.Cut(text, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("line %v is not a 'user:hash' pair", line)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("hash of user '%s' is not a bcrypt hash, create it with 'htpasswd -B'", user)
+		}
+		users[user] = []byte(hash)
+	}
+
+	return users, scanner.Err()
+}
+
+func (a *basicAuthenticator) Authenticate(req *http.Request) (*auth.Principal, error) {
+	user, password, ok := req.BasicAuth()
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+
+	hash, known := a.users[user]
+	if !known {
+		hash = dummyHash()
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !known {
+		return nil, fmt.Errorf("%w for user '%s'", auth.ErrInvalidCredentials, user)
+	}
+
+	return &auth.Principal{Name: user}, nil
+}
+
+func (a *basicAuthenticator) Challenge() string {
+	return a.challenge
+}
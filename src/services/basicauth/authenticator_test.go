@@ -0,0 +1,60 @@
+package basicauth
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	require.NoError(t, os.WriteFile(path, []byte("# users\nalice:"+string(hash)+"\n"), 0o600))
+
+	authenticator, err := New(&config.AuthBasic{HtpasswdFile: path, Realm: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, `Basic realm="test", charset="UTF-8"`, authenticator.Challenge())
+
+	testCases := []struct {
+		Name          string
+		User          string
+		Password      string
+		NoCredentials bool
+		ExpectedError error
+	}{
+		{Name: "valid", User: "alice", Password: "secret"},
+		{Name: "wrong password", User: "alice", Password: "guess", ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "unknown user", User: "bob", Password: "secret", ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "no credentials", NoCredentials: true, ExpectedError: auth.ErrNoCredentials},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if !tc.NoCredentials {
+				req.SetBasicAuth(tc.User, tc.Password)
+			}
+
+			principal, err := authenticator.Authenticate(req)
+			if tc.ExpectedError != nil {
+				assert.ErrorIs(t, err, tc.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.User, principal.Name)
+		})
+	}
+}
+
+func TestParseHtpasswdRejectsOtherHashes(t *testing.T) {
+	_, err := parseHtpasswd([]byte("alice:$apr1$abc$def\n"))
+	assert.ErrorContains(t, err, "not a bcrypt hash")
+}
@@ -0,0 +1,59 @@
+package certauth
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+)
+
+// An authenticator accepting client certificates verified by the server, optionally restricted by subject or SAN
+type certAuthenticator struct {
+	subjects []string
+	sans     []string
+}
+
+func New(config *config.AuthClientCert) *certAuthenticator {
+	return &certAuthenticator{
+		subjects: config.Subjects,
+		sans:     config.SANs,
+	}
+}
+
+func (a *certAuthenticator) Authenticate(req *http.Request) (*auth.Principal, error) {
+	certificate := auth.VerifiedClientCertificate(req)
+	if certificate == nil {
+		return nil, auth.ErrNoCredentials
+	}
+
+	if len(a.subjects) > 0 || len(a.sans) > 0 {
+		subjectMatches := slices.ContainsFunc([]string{certificate.Subject.CommonName, certificate.Subject.String()}, func(subject string) bool { return matchesAny(a.subjects, subject) })
+		sanMatches := slices.ContainsFunc(auth.SubjectAlternativeNames(certificate), func(san string) bool { return matchesAny(a.sans, san) })
+		if !subjectMatches && !sanMatches {
+			return nil, fmt.Errorf("%w: certificate '%s' is not allowed", auth.ErrInvalidCredentials, certificate.Subject)
+		}
+	}
+
+	name := certificate.Subject.CommonName
+	if name == "" {
+		name = certificate.Subject.String()
+	}
+	return &auth.Principal{Name: name, Env: auth.ClientCertificateEnv(certificate)}, nil
+}
+
+// Client certificates are requested in the TLS handshake instead
+func (a *certAuthenticator) Challenge() string {
+	return ""
+}
+
+// Returns true if the value matches one of the patterns
+func matchesAny(patterns []string, value string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+		return matched
+	})
+}
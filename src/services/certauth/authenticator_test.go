@@ -0,0 +1,57 @@
+package certauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticate(t *testing.T) {
+	certificate := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "deploy-bot", Organization: []string{"Example"}},
+		DNSNames:     []string{"deploy.clients.example.com"},
+		SerialNumber: big.NewInt(42),
+	}
+
+	testCases := []struct {
+		Name          string
+		Config        config.AuthClientCert
+		Verified      bool
+		ExpectedError error
+	}{
+		{Name: "any certificate", Verified: true},
+		{Name: "subject", Config: config.AuthClientCert{Subjects: []string{"deploy-*"}}, Verified: true},
+		{Name: "full subject", Config: config.AuthClientCert{Subjects: []string{"CN=deploy-bot,O=Example"}}, Verified: true},
+		{Name: "san", Config: config.AuthClientCert{Subjects: []string{"admin"}, SANs: []string{"*.clients.example.com"}}, Verified: true},
+		{Name: "not allowed", Config: config.AuthClientCert{Subjects: []string{"admin"}, SANs: []string{"*.admins.example.com"}}, Verified: true, ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "not verified", ExpectedError: auth.ErrNoCredentials},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}
+			if tc.Verified {
+				req.TLS.VerifiedChains = [][]*x509.Certificate{{certificate}}
+			}
+
+			principal, err := New(&tc.Config).Authenticate(req)
+			if tc.ExpectedError != nil {
+				assert.ErrorIs(t, err, tc.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "deploy-bot", principal.Name)
+			assert.Equal(t, "deploy.clients.example.com", principal.Env[auth.EnvClientSANs])
+			assert.Equal(t, "2a", principal.Env[auth.EnvClientSerial])
+		})
+	}
+}
@@ -4,13 +4,22 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"time"
+
+	"github.com/bdoerfchen/webcmd/src/logging"
 )
 
-func AccessLogMiddleware(logger *slog.Logger) func(next http.Handler) http.Handler {
+// Middleware logging each request with its response. Requests of the skipped paths are not logged
+func AccessLogMiddleware(logger *slog.Logger, skipPaths ...string) func(next http.Handler) http.Handler {
 	// Return actual middleware
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if slices.Contains(skipPaths, requestPath(req)) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
 			startTime := time.Now()
 			responseTracker := &trackingResponseWriter{ResponseWriter: w}
 
@@ -19,8 +28,8 @@ func AccessLogMiddleware(logger *slog.Logger) func(next http.Handler) http.Handl
 
 			elapsed := time.Since(startTime)
 			url := req.URL.String()
-			// Log after the request is done
-			logger.Info(fmt.Sprintf("%s %s -> %v", req.Method, url, responseTracker.StatusCode()),
+			// Log after the request is done, with the request logger carrying the request ID
+			logging.FromContextOr(req.Context(), logger).Info(fmt.Sprintf("%s %s -> %v", req.Method, url, responseTracker.StatusCode()),
 				slog.Duration("responseTime", elapsed),
 				slog.Int("size", responseTracker.BytesWritten()),
 				slog.String("userAgent", req.UserAgent()),
@@ -49,6 +58,11 @@ func (w *trackingResponseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Allows http.ResponseController to reach the connection, to flush streamed responses and hijack upgraded connections
+func (w *trackingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // Returns the number of bytes written
 func (w *trackingResponseWriter) BytesWritten() int {
 	return w.bytesWritten
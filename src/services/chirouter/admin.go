@@ -0,0 +1,221 @@
+package chirouter
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	ProblemMaintenance = "urn:webcmd:problem:maintenance"
+	ProblemNotFound    = "urn:webcmd:problem:not-found"
+	ProblemNotKillable = "urn:webcmd:problem:not-killable"
+)
+
+// A registered route, as inspected and controlled by the admin API
+type registeredRoute struct {
+	name        string // Method and full pattern (like GET /api/users/{id})
+	route       *OptimizedRoute
+	maintenance atomic.Bool
+	verifier    webhook.Verifier // Verifier of webhook deliveries, nil if the route is no webhook
+}
+
+// An execution as listed by the admin API
+type ExecutionInfo struct {
+	ID        uint64  `json:"id"`
+	Route     string  `json:"route"`
+	RequestID string  `json:"requestId,omitempty"`
+	Started   string  `json:"started"`
+	Duration  float64 `json:"durationSeconds"`
+	PIDs      []int   `json:"pids"`
+}
+
+// A route as listed by the admin API
+type RouteInfo struct {
+	Route       string   `json:"route"`
+	Kind        string   `json:"kind"`
+	Env         []string `json:"env"`
+	Auth        []string `json:"auth,omitempty"`
+	Caching     bool     `json:"caching"`
+	Maintenance bool     `json:"maintenance"`
+	Listeners   []string `json:"listeners,omitempty"`
+}
+
+// Wrap a route handler to answer with 503 Service Unavailable while the route is in maintenance
+func maintenanceHandler(registered *registeredRoute, handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !registered.maintenance.Load() {
+			handler(w, req)
+			return
+		}
+
+		w.Header().Add("Server", ServerHeader)
+		writeError(w, req, &registered.route.Route, Problem{Type: ProblemMaintenance, Status: http.StatusServiceUnavailable, Detail: "The route is in maintenance"})
+	})
+}
+
+// The admin API, which inspects and controls the registered routes
+type adminAPI struct {
+	router *chirouter
+	logger *slog.Logger
+}
+
+// Handler of the admin API. Requests have to be accepted by one of the authenticators, if there are any
+func (r *chirouter) AdminHandler(authenticators []string, logger *slog.Logger) (http.Handler, error) {
+	api := &adminAPI{router: r, logger: logger}
+	router := chi.NewRouter()
+	router.Use(
+		middleware.StripSlashes,
+		requestIDMiddleware(cmp.Or(r.modules.RequestID.Header, config.DefaultRequestIDHeader), logger),
+		middleware.Recoverer,
+		AccessLogMiddleware(logger),
+	)
+
+	if len(authenticators) > 0 {
+		accepted, err := r.authenticators.For(authenticators)
+		if err != nil {
+			return nil, err
+		}
+		route := &config.Route{Route: "admin", Auth: authenticators}
+		router.Use(func(next http.Handler) http.Handler {
+			return authHandler(route, accepted, next.ServeHTTP, logger)
+		})
+	}
+
+	router.Get("/routes", api.routes)
+	router.Put("/maintenance", api.maintenance(true))
+	router.Delete("/maintenance", api.maintenance(false))
+	router.Get("/shellpool", api.shellPool)
+	router.Delete("/cache", api.purgeCache)
+	router.Get("/executions", api.executions)
+	router.Delete("/executions/{id}", api.killExecution)
+	return router, nil
+}
+
+// List the effective routes with the env variables of their parameters
+func (a *adminAPI) routes(w http.ResponseWriter, req *http.Request) {
+	result := make([]RouteInfo, 0, len(a.router.routes))
+	for _, name := range slices.Sorted(maps.Keys(a.router.routes)) {
+		registered := a.router.routes[name]
+		result = append(result, RouteInfo{
+			Route:       name,
+			Kind:        registered.route.Kind(),
+			Env:         registered.route.parameters.EnvNames(),
+			Auth:        registered.route.Auth,
+			Caching:     registered.route.Caching,
+			Maintenance: registered.maintenance.Load(),
+			Listeners:   registered.route.Listeners,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Put the route of the "route" query parameter into maintenance, or take it out of maintenance
+func (a *adminAPI) maintenance(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		name := req.URL.Query().Get("route")
+		registered, ok := a.router.routes[name]
+		if !ok {
+			writeProblem(w, req, nil, Problem{Type: ProblemNotFound, Status: http.StatusNotFound, Detail: "There is no route '" + name + "'"})
+			return
+		}
+
+		if registered.maintenance.Swap(enabled) != enabled {
+			a.logger.Info("route maintenance changed", slog.String("route", name), slog.Bool("maintenance", enabled))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Show the state of the shell pool
+func (a *adminAPI) shellPool(w http.ResponseWriter, req *http.Request) {
+	for _, executer := range a.router.executerCollection.Available() {
+		if pool, ok := executer.(interface {
+			Capacity() int
+			Available() int
+		}); ok {
+			writeJSON(w, http.StatusOK, map[string]int{"capacity": pool.Capacity(), "available": pool.Available()})
+			return
+		}
+	}
+
+	writeProblem(w, req, nil, Problem{Type: ProblemNotFound, Status: http.StatusNotFound, Detail: "The shell pool is not enabled"})
+}
+
+// Purge the cached responses of the route of the "route" query parameter, or of all routes without parameter
+func (a *adminAPI) purgeCache(w http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get("route")
+	if _, ok := a.router.routes[name]; name != "" && !ok {
+		writeProblem(w, req, nil, Problem{Type: ProblemNotFound, Status: http.StatusNotFound, Detail: "There is no route '" + name + "'"})
+		return
+	}
+
+	var purged int
+	if a.router.cacher != nil {
+		purged = a.router.cacher.Purge(name)
+	}
+	a.logger.Info("cache purged", slog.String("route", name), slog.Int("responses", purged))
+	writeJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// List the running executions with their duration and local processes
+func (a *adminAPI) executions(w http.ResponseWriter, req *http.Request) {
+	result := []ExecutionInfo{}
+	for _, execution := range a.router.executions.List() {
+		result = append(result, ExecutionInfo{
+			ID:        execution.ID,
+			Route:     execution.Route,
+			RequestID: execution.RequestID,
+			Started:   execution.Started.UTC().Format(time.RFC3339Nano),
+			Duration:  time.Since(execution.Started).Seconds(),
+			PIDs:      execution.PIDs,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Kill the local process groups of a running execution
+func (a *adminAPI) killExecution(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(req, "id"), 10, 64)
+	found := false
+	if err == nil {
+		found, err = a.router.executions.Kill(id)
+	}
+	switch {
+	case !found:
+		writeProblem(w, req, nil, Problem{Type: ProblemNotFound, Status: http.StatusNotFound, Detail: "There is no running execution with this id"})
+	case errors.Is(err, execution.ErrNoLocalProcess):
+		writeProblem(w, req, nil, Problem{Type: ProblemNotKillable, Status: http.StatusConflict, Detail: "The execution has no local process to kill"})
+	case err != nil:
+		writeProblem(w, req, nil, Problem{Type: ProblemNotKillable, Status: http.StatusInternalServerError, Detail: err.Error()})
+	default:
+		a.logger.Info("execution killed", slog.Uint64("id", id))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(content)
+}
@@ -0,0 +1,89 @@
+package chirouter
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// An executer blocking until it is released
+type blockingExecuter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *blockingExecuter) Execute(ctx context.Context, config execution.Config) (*process.Process, int, error) {
+	e.started <- struct{}{}
+	<-e.release
+	return &process.Process{}, 0, nil
+}
+
+func (e *blockingExecuter) Describe() (execution.ExecMode, []any) {
+	return execution.ModeProc, []any{}
+}
+
+func TestAdminAPI(t *testing.T) {
+	executer := &blockingExecuter{started: make(chan struct{}), release: make(chan struct{})}
+	var executers execution.ExecuterCollection
+	executers.Add(executer)
+
+	route := config.DefaultRoute()
+	route.Route = "/users/{id}"
+	route.Exec.Proc = &config.ExecProc{Path: "test"}
+	route.Check()
+
+	router := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}})
+	require.NoError(t, router.Register(context.Background(), []config.Route{route}, nil))
+	admin, err := router.AdminHandler(nil, slog.Default())
+	require.NoError(t, err)
+
+	serve := func(handler http.Handler, method, target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+		return recorder
+	}
+
+	// Routes are listed with their env variables
+	var routes []RouteInfo
+	require.NoError(t, json.Unmarshal(serve(admin, http.MethodGet, "/routes").Body.Bytes(), &routes))
+	assert.Equal(t, []RouteInfo{{Route: "GET /users/{id}", Kind: "exec", Env: []string{"WC_ID"}}}, routes)
+
+	// Routes in maintenance are not executed
+	assert.Equal(t, http.StatusNoContent, serve(admin, http.MethodPut, "/maintenance?route=GET+/users/{id}").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(router.Handler(), http.MethodGet, "/users/1").Code)
+	assert.Equal(t, http.StatusNoContent, serve(admin, http.MethodDelete, "/maintenance?route=GET+/users/{id}").Code)
+	assert.Equal(t, http.StatusNotFound, serve(admin, http.MethodPut, "/maintenance?route=GET+/unknown").Code)
+
+	// Maintenance is kept by the router replacing this one on reload
+	assert.Equal(t, http.StatusNoContent, serve(admin, http.MethodPut, "/maintenance?route=GET+/users/{id}").Code)
+	reloaded := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}})
+	require.NoError(t, reloaded.Register(context.Background(), []config.Route{route}, nil))
+	reloaded.Inherit(router)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(reloaded.Handler(), http.MethodGet, "/users/1").Code)
+	assert.Equal(t, http.StatusNoContent, serve(admin, http.MethodDelete, "/maintenance?route=GET+/users/{id}").Code)
+
+	// Running executions are listed, but can only be killed with local processes
+	done := make(chan int)
+	go func() { done <- serve(router.Handler(), http.MethodGet, "/users/1").Code }()
+	<-executer.started
+	var executions []ExecutionInfo
+	require.NoError(t, json.Unmarshal(serve(admin, http.MethodGet, "/executions").Body.Bytes(), &executions))
+	require.Len(t, executions, 1)
+	assert.Equal(t, "GET /users/{id}", executions[0].Route)
+	assert.Empty(t, executions[0].PIDs)
+	assert.Equal(t, http.StatusConflict, serve(admin, http.MethodDelete, "/executions/1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(admin, http.MethodDelete, "/executions/2").Code)
+
+	close(executer.release)
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, "[]", serve(admin, http.MethodGet, "/executions").Body.String())
+}
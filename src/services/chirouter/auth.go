@@ -0,0 +1,89 @@
+package chirouter
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/params"
+)
+
+const (
+	ProblemUnauthorized = "urn:webcmd:problem:unauthorized"
+	ProblemForbidden    = "urn:webcmd:problem:forbidden"
+)
+
+// Middleware adding the identity of a verified client certificate to the env variables of the request
+func clientCertMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if certificate := auth.VerifiedClientCertificate(req); certificate != nil {
+			req = req.WithContext(params.AddEnvToContext(req.Context(), auth.ClientCertificateEnv(certificate)))
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Wrap a route handler to require one of its authenticators to accept the request. The principal is added to the env variables of the request
+func authHandler(route *config.Route, authenticators []auth.Authenticator, handler http.HandlerFunc, logger *slog.Logger) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for i, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(req)
+			if err != nil {
+				if !errors.Is(err, auth.ErrNoCredentials) {
+					logger.WarnContext(req.Context(),
+						"authentication failed",
+						slog.String("error", err.Error()),
+						slog.String("authenticator", route.Auth[i]),
+						slog.String("route", route.Route),
+					)
+				}
+				continue
+			}
+
+			principal.Authenticator = route.Auth[i]
+			if missing := missingRequirement(route, principal); missing != "" {
+				logger.WarnContext(req.Context(),
+					"authorization failed",
+					slog.String("user", principal.Name),
+					slog.String("missing", missing),
+					slog.String("route", route.Route),
+				)
+				w.Header().Add("Server", ServerHeader)
+				writeError(w, req, route, Problem{Type: ProblemForbidden, Status: http.StatusForbidden, Detail: "The credentials do not grant access to this route"})
+				return
+			}
+
+			ctx := auth.AddPrincipalToContext(req.Context(), principal)
+			ctx = params.AddEnvToContext(ctx, principal.EnvMap())
+			handler(w, req.WithContext(ctx))
+			return
+		}
+
+		// No authenticator accepted the request
+		for _, authenticator := range authenticators {
+			if challenge := authenticator.Challenge(); challenge != "" {
+				w.Header().Add("WWW-Authenticate", challenge)
+			}
+		}
+		w.Header().Add("Server", ServerHeader)
+		writeError(w, req, route, Problem{Type: ProblemUnauthorized, Status: http.StatusUnauthorized, Detail: "The request requires valid credentials"})
+	})
+}
+
+// Returns the first scope or claim required by the route that the principal lacks, or an empty string
+func missingRequirement(route *config.Route, principal *auth.Principal) string {
+	for _, scope := range route.RequireScopes {
+		if !principal.HasScope(scope) {
+			return "scope " + scope
+		}
+	}
+	for claim, value := range route.RequireClaims {
+		if !principal.HasClaim(claim, value) {
+			return "claim " + claim
+		}
+	}
+
+	return ""
+}
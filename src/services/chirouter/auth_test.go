@@ -0,0 +1,136 @@
+package chirouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/bdoerfchen/webcmd/src/services/springercacher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// An authenticator accepting a single header value
+type fakeAuthenticator struct {
+	header string
+	value  string
+}
+
+func (a *fakeAuthenticator) Authenticate(req *http.Request) (*auth.Principal, error) {
+	switch req.Header.Get(a.header) {
+	case "":
+		return nil, auth.ErrNoCredentials
+	case a.value:
+		return &auth.Principal{Name: "user-" + a.value, Claims: map[string]any{"scope": "read", "roles": []any{"role-" + a.value}, "org": map[string]any{"name": "org-" + a.value}}}, nil
+	default:
+		return nil, auth.ErrInvalidCredentials
+	}
+}
+
+func (a *fakeAuthenticator) Challenge() string {
+	return a.header
+}
+
+func TestAuth(t *testing.T) {
+	authenticators := auth.Collection{
+		"first":  &fakeAuthenticator{header: "X-First", value: "1"},
+		"second": &fakeAuthenticator{header: "X-Second", value: "2"},
+	}
+	protected := testRoute("/protected", "")
+	protected.Auth = []string{"first", "second"}
+	protected.Parameters = []config.RouteParameter{{Source: config.ParamSourceHeader, Name: "X-Spoof", As: auth.EnvUser}}
+	scoped := testRoute("/scoped", "")
+	scoped.Auth = []string{"first", "second"}
+	scoped.RequireScopes = []string{"read"}
+	scoped.RequireClaims = map[string]string{"roles": "role-1"}
+	scoped.Parameters = []config.RouteParameter{{Source: config.ParamSourceClaim, Name: "org.name", As: auth.EnvUser + "_ORG"}}
+	routes := []config.Route{protected, scoped, testRoute("/public", "")}
+
+	var executers execution.ExecuterCollection
+	executer := &fakeExecuter{echoEnv: auth.EnvUser}
+	executers.Add(executer)
+	for i := range routes {
+		routes[i].Exec.Proc = &config.ExecProc{Path: "test"}
+		routes[i].Check()
+	}
+	router := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}, Authenticators: authenticators})
+	require.NoError(t, router.Register(context.Background(), routes, nil))
+
+	testCases := []struct {
+		Name               string
+		Path               string
+		Headers            map[string]string
+		ExpectedStatusCode int
+		ExpectedBody       string
+		ExpectedChallenges []string
+		ExpectedOrg        string
+	}{
+		{Name: "public", Path: "/public", ExpectedStatusCode: 200},
+		{Name: "no credentials", Path: "/protected", ExpectedStatusCode: 401, ExpectedChallenges: []string{"X-First", "X-Second"}},
+		{Name: "invalid credentials", Path: "/protected", Headers: map[string]string{"X-First": "0"}, ExpectedStatusCode: 401, ExpectedChallenges: []string{"X-First", "X-Second"}},
+		{Name: "first authenticator", Path: "/protected", Headers: map[string]string{"X-First": "1"}, ExpectedStatusCode: 200, ExpectedBody: "user-1"},
+		{Name: "second authenticator", Path: "/protected", Headers: map[string]string{"X-First": "0", "X-Second": "2"}, ExpectedStatusCode: 200, ExpectedBody: "user-2"},
+		{Name: "principal not spoofable", Path: "/protected", Headers: map[string]string{"X-Second": "2", "X-Spoof": "admin"}, ExpectedStatusCode: 200, ExpectedBody: "user-2"},
+		{Name: "required claims", Path: "/scoped", Headers: map[string]string{"X-First": "1"}, ExpectedStatusCode: 200, ExpectedBody: "user-1", ExpectedOrg: "org-1"},
+		{Name: "missing claim", Path: "/scoped", Headers: map[string]string{"X-Second": "2"}, ExpectedStatusCode: 403},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			for header, value := range tc.Headers {
+				req.Header.Set(header, value)
+			}
+			recorder := httptest.NewRecorder()
+			router.Handler().ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.ExpectedStatusCode, recorder.Code)
+			assert.Equal(t, tc.ExpectedBody, recorder.Body.String())
+			assert.Equal(t, tc.ExpectedChallenges, recorder.Header().Values("WWW-Authenticate"))
+			if tc.ExpectedOrg != "" {
+				assert.Equal(t, tc.ExpectedOrg, executer.lastEnv[auth.EnvUser+"_ORG"])
+			}
+		})
+	}
+}
+
+func TestAuthCaching(t *testing.T) {
+	authenticators := auth.Collection{
+		"first":  &fakeAuthenticator{header: "X-First", value: "1"},
+		"second": &fakeAuthenticator{header: "X-Second", value: "2"},
+	}
+	route := testRoute("/cached", "")
+	route.Auth = []string{"first", "second"}
+	route.Caching = true
+	route.Exec.Proc = &config.ExecProc{Path: "test"}
+	route.Check()
+
+	var executers execution.ExecuterCollection
+	executers.Add(&fakeExecuter{echoEnv: auth.EnvUser})
+	cacher, err := springercacher.New(&config.CacheConfig{MaxResponsesCached: 10, TTL: timem.Duration(time.Minute)})
+	require.NoError(t, err)
+	router := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}, Cacher: cacher, Authenticators: authenticators})
+	require.NoError(t, router.Register(context.Background(), []config.Route{route}, nil))
+
+	request := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/cached", nil)
+		req.Header.Set(header, value)
+		recorder := httptest.NewRecorder()
+		router.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Each principal gets its own cached response, which shared caches must not store
+	for range 2 {
+		first, second := request("X-First", "1"), request("X-Second", "2")
+		assert.Equal(t, "user-1", first.Body.String())
+		assert.Equal(t, "user-2", second.Body.String())
+		assert.Contains(t, first.Header().Get("Cache-Control"), "private")
+	}
+}
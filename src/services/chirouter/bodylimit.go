@@ -0,0 +1,26 @@
+package chirouter
+
+import (
+	"net/http"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+)
+
+// Answer requests with a declared body larger than the limit of the server with 413 Request Entity Too Large.
+// Bodies without declared length are cut off at the limit by the server
+func bodyLimitHandler(route *config.Route, handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if limit := server.BodyLimitFromContext(req.Context()); limit > 0 && req.ContentLength > limit {
+			// The body is not read, so the connection can not be reused. HTTP/2 only resets the stream
+			if req.ProtoMajor < 2 {
+				w.Header().Set("Connection", "close")
+			}
+			w.Header().Add("Server", ServerHeader)
+			writeError(w, req, route, Problem{Type: ProblemBodyTooLarge, Status: http.StatusRequestEntityTooLarge, Detail: "The request body is too large"})
+			return
+		}
+
+		handler(w, req)
+	})
+}
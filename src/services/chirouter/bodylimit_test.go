@@ -0,0 +1,90 @@
+package chirouter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimit(t *testing.T) {
+	route := testRoute("/upload", config.ErrorFormatProblem)
+	route.Method = http.MethodPost
+	route.AllowBody = true
+	path := filepath.Join(t.TempDir(), "webcmd.sock")
+	s, err := server.New(server.Config{Listeners: []server.ListenerConfig{{Type: server.ListenerUnix, Path: path}}, MaxBodyBytes: 4, H2C: true})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx, testHandler(t, &fakeExecuter{stdout: "done"}, route))
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	http1, h2c := new(http.Protocols), new(http.Protocols)
+	http1.SetHTTP1(true)
+	h2c.SetUnencryptedHTTP2(true)
+
+	testCases := []struct {
+		Name               string
+		Protocols          *http.Protocols
+		Body               string
+		ExpectedStatusCode int
+		ExpectedProto      int
+		ExpectedClose      bool
+	}{
+		{Name: "within limit", Protocols: http1, Body: "abcd", ExpectedStatusCode: http.StatusOK, ExpectedProto: 1},
+		{Name: "too large", Protocols: http1, Body: "abcde", ExpectedStatusCode: http.StatusRequestEntityTooLarge, ExpectedProto: 1, ExpectedClose: true},
+		{Name: "within limit with h2c", Protocols: h2c, Body: "abcd", ExpectedStatusCode: http.StatusOK, ExpectedProto: 2},
+		{Name: "too large with h2c", Protocols: h2c, Body: "abcde", ExpectedStatusCode: http.StatusRequestEntityTooLarge, ExpectedProto: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			transport := &http.Transport{Protocols: tc.Protocols, DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			}}
+			defer transport.CloseIdleConnections()
+			resp, err := (&http.Client{Transport: transport}).Post("http://webcmd/upload", "text/plain", strings.NewReader(tc.Body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.ExpectedStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.ExpectedProto, resp.ProtoMajor)
+			// The client reads the Connection header into Close
+			assert.Equal(t, tc.ExpectedClose, resp.Close)
+			if tc.ExpectedStatusCode == http.StatusRequestEntityTooLarge {
+				assert.Equal(t, ProblemContentType, resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestBodyLimitConnection(t *testing.T) {
+	route := testRoute("/upload", "")
+	handler := bodyLimitHandler(&route, func(w http.ResponseWriter, req *http.Request) {})
+
+	// HTTP/2 forbids connection-specific headers. The Go server drops them itself, so they are checked on the handler
+	for _, proto := range []int{1, 2} {
+		req := httptest.NewRequestWithContext(server.WithBodyLimit(context.Background(), 4), http.MethodPost, "/upload", strings.NewReader("abcde"))
+		req.ProtoMajor = proto
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Equal(t, proto == 1, recorder.Header().Get("Connection") == "close", "HTTP/%v", proto)
+	}
+}
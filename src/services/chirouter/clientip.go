@@ -0,0 +1,80 @@
+package chirouter
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+)
+
+const ProblemAddressNotAllowed = "urn:webcmd:problem:address-not-allowed"
+
+// Middleware replacing the remote address with the client address forwarded by trusted proxies, or by any client of listeners trusting them.
+// Forwarded headers of other clients are ignored, as they could be forged to bypass address lists
+func clientIPMiddleware(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			remote, ok := remoteAddr(req)
+			if server.TrustsForwarded(req.Context()) || (ok && config.ContainsAddr(trustedProxies, remote)) {
+				if client, ok := forwardedAddr(req, trustedProxies); ok {
+					req.RemoteAddr = client.String()
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// Returns the client address of a request forwarded by trusted proxies
+func forwardedAddr(req *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	// Each proxy appends the address it received the request from, so the last untrusted one is the client
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		if i == 0 || !config.ContainsAddr(trustedProxies, addr) {
+			return addr.Unmap(), true
+		}
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(req.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap(), true
+	}
+
+	return netip.Addr{}, false
+}
+
+// Returns the address of the client, which is either directly connected or forwarded by a trusted proxy
+func remoteAddr(req *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// Answer requests of clients that are denied or not allowed by the address lists with 403 Forbidden.
+// Clients without address, like those of unix sockets, are only refused by allow lists
+func addressFilter(allowFrom, denyFrom []netip.Prefix, route *config.Route, handler http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		addr, ok := remoteAddr(req)
+		if (!ok && len(allowFrom) > 0) || (ok && (config.ContainsAddr(denyFrom, addr) || (len(allowFrom) > 0 && !config.ContainsAddr(allowFrom, addr)))) {
+			w.Header().Add("Server", ServerHeader)
+			writeError(w, req, route, Problem{Type: ProblemAddressNotAllowed, Status: http.StatusForbidden, Detail: "The client address is not allowed to access this route"})
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,78 @@
+package chirouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressLists(t *testing.T) {
+	internal := testRoute("/internal", "")
+	internal.AllowFrom = []string{"10.0.0.0/8", "2001:db8::/32"}
+	internal.DenyFrom = []string{"10.0.0.13"}
+	routes := []config.Route{internal, testRoute("/public", "")}
+	modules := &config.ModulesConfig{
+		DenyFrom:       []string{"198.51.100.0/24"},
+		TrustedProxies: []string{"192.0.2.0/24"},
+	}
+
+	var executers execution.ExecuterCollection
+	executers.Add(&fakeExecuter{stdout: "ok"})
+	for i := range routes {
+		routes[i].Exec.Proc = &config.ExecProc{Path: "test"}
+		routes[i].Check()
+	}
+	router := New(Options{Executers: &executers, Modules: modules})
+	require.NoError(t, router.Register(context.Background(), routes, nil))
+	handler := router.Handler()
+
+	testCases := []struct {
+		Name               string
+		Path               string
+		RemoteAddr         string
+		ForwardedFor       string
+		TrustForwarded     bool
+		ExpectedStatusCode int
+	}{
+		{Name: "allowed", Path: "/internal", RemoteAddr: "10.1.2.3:1000", ExpectedStatusCode: 200},
+		{Name: "allowed ipv6", Path: "/internal", RemoteAddr: "[2001:db8::1]:1000", ExpectedStatusCode: 200},
+		{Name: "not allowed", Path: "/internal", RemoteAddr: "203.0.113.1:1000", ExpectedStatusCode: 403},
+		{Name: "denied address in allowed range", Path: "/internal", RemoteAddr: "10.0.0.13:1000", ExpectedStatusCode: 403},
+		{Name: "public", Path: "/public", RemoteAddr: "203.0.113.1:1000", ExpectedStatusCode: 200},
+		{Name: "globally denied", Path: "/public", RemoteAddr: "198.51.100.7:1000", ExpectedStatusCode: 403},
+		{Name: "forged header of untrusted client", Path: "/internal", RemoteAddr: "203.0.113.1:1000", ForwardedFor: "10.1.2.3", ExpectedStatusCode: 403},
+		{Name: "forwarded by trusted proxy", Path: "/internal", RemoteAddr: "192.0.2.10:1000", ForwardedFor: "10.1.2.3", ExpectedStatusCode: 200},
+		{Name: "forged hop before trusted proxy", Path: "/internal", RemoteAddr: "192.0.2.10:1000", ForwardedFor: "10.1.2.3, 203.0.113.1", ExpectedStatusCode: 403},
+		{Name: "chain of trusted proxies", Path: "/internal", RemoteAddr: "192.0.2.10:1000", ForwardedFor: "10.1.2.3, 192.0.2.11", ExpectedStatusCode: 200},
+		{Name: "globally denied behind proxy", Path: "/public", RemoteAddr: "192.0.2.10:1000", ForwardedFor: "198.51.100.7", ExpectedStatusCode: 403},
+		{Name: "unix client without deny", Path: "/public", RemoteAddr: "", ExpectedStatusCode: 200},
+		{Name: "unix client not allowed", Path: "/internal", RemoteAddr: "", ExpectedStatusCode: 403},
+		{Name: "ignored header of unix client", Path: "/internal", RemoteAddr: "", ForwardedFor: "10.1.2.3", ExpectedStatusCode: 403},
+		{Name: "forwarded on trusting listener", Path: "/internal", RemoteAddr: "", ForwardedFor: "10.1.2.3", TrustForwarded: true, ExpectedStatusCode: 200},
+		{Name: "globally denied on trusting listener", Path: "/public", RemoteAddr: "", ForwardedFor: "198.51.100.7", TrustForwarded: true, ExpectedStatusCode: 403},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			req.RemoteAddr = tc.RemoteAddr
+			if tc.ForwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.ForwardedFor)
+			}
+			if tc.TrustForwarded {
+				req = req.WithContext(server.WithTrustedForwarding(req.Context()))
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.ExpectedStatusCode, recorder.Code)
+		})
+	}
+}
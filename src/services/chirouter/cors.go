@@ -0,0 +1,162 @@
+package chirouter
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+	"github.com/go-chi/chi/v5"
+)
+
+// Response headers set by CORS handling
+var corsHeaders = []string{
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Allow-Headers",
+	"Access-Control-Expose-Headers",
+	"Access-Control-Max-Age",
+}
+
+// Middleware answering CORS preflight requests of all patterns, with the CORS config of the route for the requested method. Routes are not executed
+func (r *chirouter) corsPreflightMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		method := req.Header.Get("Access-Control-Request-Method")
+		if req.Method != http.MethodOptions || origin == "" || method == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		// Every pattern has an OPTIONS route, which is used to find the pattern of this request
+		routes, ok := r.patterns[r.router.Find(chi.NewRouteContext(), http.MethodOptions, requestPath(req))]
+		if !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+		listener := server.ListenerFromContext(req.Context())
+		if _, ok := routes.allowHeader(listener); !ok {
+			http.NotFound(w, req)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+		w.Header().Add("Server", ServerHeader)
+
+		// Without CORS headers the browser rejects the actual request
+		cors := routes.corsFor(method, listener)
+		requestedHeaders := req.Header.Get("Access-Control-Request-Headers")
+		if cors.Enabled() && cors.AllowsOrigin(origin) && corsAllowsMethod(cors, method) && corsAllowsHeaders(cors, requestedHeaders) {
+			setCORSHeaders(w.Header(), cors, origin)
+			w.Header().Set("Access-Control-Allow-Methods", method)
+			if requestedHeaders != "" {
+				w.Header().Set("Access-Control-Allow-Headers", requestedHeaders)
+			}
+			if cors.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(time.Duration(cors.MaxAge).Seconds())))
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Returns the CORS config of the route registered for the method, if it is served on the listener. HEAD requests use the GET route
+func (p *patternRoutes) corsFor(method, listener string) *config.CORSConfig {
+	methods := p.methodsFor(listener)
+	if slices.Contains(methods, method) {
+		return p.cors[method]
+	}
+	if method == http.MethodHead && slices.Contains(methods, http.MethodGet) {
+		return p.cors[http.MethodGet]
+	}
+	return nil
+}
+
+// Without configured methods, the method of the route is allowed
+func corsAllowsMethod(cors *config.CORSConfig, method string) bool {
+	return len(cors.AllowedMethods) == 0 || slices.Contains(cors.AllowedMethods, method)
+}
+
+// Returns true if all of the comma separated headers are allowed
+func corsAllowsHeaders(cors *config.CORSConfig, headers string) bool {
+	if headers == "" || slices.Contains(cors.AllowedHeaders, "*") {
+		return true
+	}
+
+	for header := range strings.SplitSeq(headers, ",") {
+		header = strings.TrimSpace(header)
+		if !slices.ContainsFunc(cors.AllowedHeaders, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// Set the headers shared by preflight and actual responses
+func setCORSHeaders(header http.Header, cors *config.CORSConfig, origin string) {
+	if slices.Contains(cors.AllowedOrigins, "*") && !cors.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if cors.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Wrap a route handler to add CORS headers to its responses
+func corsHandler(cors *config.CORSConfig, handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+
+		// Headers are applied right before the response is written, as cached responses carry the headers of their first request
+		handler(&headerHookWriter{ResponseWriter: w, hook: func(header http.Header) {
+			for _, name := range corsHeaders {
+				header.Del(name)
+			}
+			if !slices.Contains(header.Values("Vary"), "Origin") {
+				header.Add("Vary", "Origin")
+			}
+
+			if origin == "" || !cors.AllowsOrigin(origin) {
+				return
+			}
+			setCORSHeaders(header, cors, origin)
+			if len(cors.ExposedHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(cors.ExposedHeaders, ", "))
+			}
+		}}, req)
+	})
+}
+
+// A ResponseWriter calling a hook on the headers before they are written
+type headerHookWriter struct {
+	http.ResponseWriter
+	hook    func(http.Header)
+	written bool
+}
+
+func (w *headerHookWriter) WriteHeader(statusCode int) {
+	if !w.written {
+		w.written = true
+		w.hook(w.Header())
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *headerHookWriter) Write(content []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(content)
+}
+
+// Allows http.ResponseController to access the underlying writer. The outer tracking writers must unwrap as well
+func (w *headerHookWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
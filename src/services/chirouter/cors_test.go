@@ -0,0 +1,119 @@
+package chirouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	modules := config.ModulesConfig{CORS: &config.CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedHeaders: []string{"Content-Type"},
+		ExposedHeaders: []string{"X-Request-Id"},
+		MaxAge:         timem.Duration(time.Hour),
+	}}
+	post := testRoute("/items", "")
+	post.Method = http.MethodPost
+	credentials := testRoute("/account", "")
+	credentials.CORS = &config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	disabled := testRoute("/internal", "")
+	disabled.CORS = &config.CORSConfig{}
+	routes := []config.Route{testRoute("/items", ""), post, credentials, disabled}
+
+	var executers execution.ExecuterCollection
+	executers.Add(&fakeExecuter{stdout: "body"})
+	for i := range routes {
+		routes[i].Exec.Proc = &config.ExecProc{Path: "test"}
+		routes[i].Check()
+	}
+	router := New(Options{Executers: &executers, Modules: &modules})
+	require.NoError(t, router.Register(context.Background(), routes, nil))
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		Headers            map[string]string
+		ExpectedStatusCode int
+		ExpectedHeaders    map[string]string
+	}{
+		{
+			Name: "preflight", Method: http.MethodOptions, Path: "/items",
+			Headers:            map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "content-type"},
+			ExpectedStatusCode: 204,
+			ExpectedHeaders:    map[string]string{"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Allow-Methods": "POST", "Access-Control-Allow-Headers": "content-type", "Access-Control-Max-Age": "3600"},
+		},
+		{
+			Name: "preflight with other origin", Method: http.MethodOptions, Path: "/items",
+			Headers:            map[string]string{"Origin": "https://example.org", "Access-Control-Request-Method": "POST"},
+			ExpectedStatusCode: 204,
+			ExpectedHeaders:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			Name: "preflight with header not allowed", Method: http.MethodOptions, Path: "/items",
+			Headers:            map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "Authorization"},
+			ExpectedStatusCode: 204,
+			ExpectedHeaders:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			Name: "preflight with method of no route", Method: http.MethodOptions, Path: "/items",
+			Headers:            map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "DELETE"},
+			ExpectedStatusCode: 204,
+			ExpectedHeaders:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			Name: "options without preflight", Method: http.MethodOptions, Path: "/items",
+			ExpectedStatusCode: 204,
+			ExpectedHeaders:    map[string]string{"Allow": "GET, HEAD, OPTIONS, POST", "Access-Control-Allow-Origin": ""},
+		},
+		{
+			Name: "actual request", Method: http.MethodGet, Path: "/items",
+			Headers:            map[string]string{"Origin": "https://app.example.com"},
+			ExpectedStatusCode: 200,
+			ExpectedHeaders:    map[string]string{"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Expose-Headers": "X-Request-Id", "Vary": "Origin"},
+		},
+		{
+			Name: "actual request with other origin", Method: http.MethodGet, Path: "/items",
+			Headers:            map[string]string{"Origin": "https://example.org"},
+			ExpectedStatusCode: 200,
+			ExpectedHeaders:    map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			Name: "credentials echo origin", Method: http.MethodGet, Path: "/account",
+			Headers:            map[string]string{"Origin": "https://example.org"},
+			ExpectedStatusCode: 200,
+			ExpectedHeaders:    map[string]string{"Access-Control-Allow-Origin": "https://example.org", "Access-Control-Allow-Credentials": "true"},
+		},
+		{
+			Name: "disabled for route", Method: http.MethodGet, Path: "/internal",
+			Headers:            map[string]string{"Origin": "https://app.example.com"},
+			ExpectedStatusCode: 200,
+			ExpectedHeaders:    map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.Method, tc.Path, nil)
+			for header, value := range tc.Headers {
+				req.Header.Set(header, value)
+			}
+			recorder := httptest.NewRecorder()
+			router.Handler().ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.ExpectedStatusCode, recorder.Code)
+			for header, value := range tc.ExpectedHeaders {
+				assert.Equal(t, value, recorder.Header().Get(header), header)
+			}
+		})
+	}
+}
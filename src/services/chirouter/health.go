@@ -0,0 +1,39 @@
+package chirouter
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Answer as long as the process is able to serve requests
+func livenessHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte("ok\n"))
+}
+
+// Answer with 503 Service Unavailable and the names of the failing checks, if webcmd is not ready.
+// Their output may contain internal details, so it is only logged by the monitor
+func (r *chirouter) readinessHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	var failures map[string]string
+	if r.health != nil {
+		failures = r.health.Failures()
+	}
+	if len(failures) == 0 {
+		w.Write([]byte("ok\n"))
+		return
+	}
+
+	var body strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(failures)) {
+		fmt.Fprintf(&body, "%s: failed\n", name)
+	}
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write([]byte(body.String()))
+}
@@ -0,0 +1,66 @@
+package chirouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A monitor with fixed checks
+type fakeMonitor map[string]error
+
+func (m fakeMonitor) AddCheck(name string, check func() error) {}
+
+func (m fakeMonitor) RemoveCheck(name string) {}
+
+func (m fakeMonitor) Failures() map[string]string {
+	failures := make(map[string]string)
+	for name, err := range m {
+		if err != nil {
+			failures[name] = err.Error()
+		}
+	}
+	return failures
+}
+
+func TestHealth(t *testing.T) {
+	testCases := []struct {
+		Name               string
+		Monitor            fakeMonitor
+		Path               string
+		ExpectedStatusCode int
+		ExpectedBody       string
+	}{
+		{Name: "liveness", Monitor: fakeMonitor{"pool": fmt.Errorf("empty")}, Path: "/healthz", ExpectedStatusCode: 200, ExpectedBody: "ok\n"},
+		{Name: "ready", Monitor: fakeMonitor{"pool": nil}, Path: "/readyz", ExpectedStatusCode: 200, ExpectedBody: "ok\n"},
+		{Name: "not ready", Monitor: fakeMonitor{"pool": fmt.Errorf("empty"), "db": fmt.Errorf("down"), "disk": nil}, Path: "/readyz", ExpectedStatusCode: 503, ExpectedBody: "db: failed\npool: failed\n"},
+		{Name: "without monitor", Path: "/readyz", ExpectedStatusCode: 200, ExpectedBody: "ok\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var executers execution.ExecuterCollection
+			modules := &config.ModulesConfig{Health: config.HealthConfig{LivenessPath: config.DefaultLivenessPath, ReadinessPath: config.DefaultReadinessPath}}
+			var router *chirouter
+			if tc.Monitor != nil {
+				router = New(Options{Executers: &executers, Modules: modules, Monitor: tc.Monitor})
+			} else {
+				router = New(Options{Executers: &executers, Modules: modules})
+			}
+			require.NoError(t, router.Register(context.Background(), nil, nil))
+
+			recorder := httptest.NewRecorder()
+			router.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.Path, nil))
+
+			assert.Equal(t, tc.ExpectedStatusCode, recorder.Code)
+			assert.Equal(t, tc.ExpectedBody, recorder.Body.String())
+		})
+	}
+}
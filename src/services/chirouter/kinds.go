@@ -0,0 +1,224 @@
+package chirouter
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/params"
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	ProblemUpstreamFailed  = "urn:webcmd:problem:upstream-failed"
+	ProblemInvalidRedirect = "urn:webcmd:problem:invalid-redirect"
+)
+
+// Returns the handler for routes that do not execute anything
+func kindHandlerFor(route *OptimizedRoute, logger *slog.Logger) http.HandlerFunc {
+	var handler http.HandlerFunc
+	switch route.Kind() {
+	case "static":
+		handler = staticHandler(route.Static)
+	case "redirect":
+		handler = redirectHandler(route)
+	case "files":
+		handler = filesHandler(route.Files)
+	case "proxy":
+		handler = proxyHandler(route, logger)
+	default:
+		// Should not happen, as the app detects this case on config check and exits
+		panic("missing route kind config")
+	}
+
+	// Set default headers for all kinds
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for header, value := range route.Headers {
+			w.Header().Add(header, value)
+		}
+		w.Header().Add("Server", ServerHeader)
+
+		handler(w, req)
+	})
+}
+
+// Respond with a fixed status and body
+func staticHandler(static *config.RouteStatic) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(static.StatusCode)
+		w.Write([]byte(static.Body))
+	})
+}
+
+// Redirect to the location with expanded parameters
+func redirectHandler(route *OptimizedRoute) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		env := route.parameters.For(req)
+		maps.Copy(env, params.EnvFromContext(req.Context()))
+		location := os.Expand(route.Redirect.Location, func(name string) string { return env[name] })
+		if leavesHost(route.Redirect.Location, location) {
+			writeError(w, req, &route.Route, Problem{Type: ProblemInvalidRedirect, Status: http.StatusBadRequest, Detail: "The parameters expand to a location on another host"})
+			return
+		}
+
+		http.Redirect(w, req, location, route.Redirect.StatusCode)
+	})
+}
+
+// Reports whether a relative location template expanded to a location on another host,
+// like /${WC_NEXT} with a parameter of /evil.example. Browsers also read /\ as //
+func leavesHost(template, location string) bool {
+	if !strings.HasPrefix(template, "/") || strings.HasPrefix(template, "//") {
+		return false
+	}
+	u, err := url.Parse(location)
+	return err != nil || u.Scheme != "" || u.Host != "" || strings.HasPrefix(location, "/\\")
+}
+
+// Serve files of the directory, with the wildcard part of the route as path
+func filesHandler(files *config.RouteFiles) http.HandlerFunc {
+	root := http.Dir(files.Directory)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := path.Clean("/" + chi.URLParam(req, "*"))
+		// Dotfiles like .git or .env are only served if enabled
+		if !files.Hidden && isHidden(name) {
+			http.NotFound(w, req)
+			return
+		}
+		file, err := root.Open(name)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+
+		if info.IsDir() {
+			// Directories need a trailing slash for relative links to work
+			if !strings.HasSuffix(req.URL.Path, "/") {
+				target := req.URL.Path + "/"
+				if req.URL.RawQuery != "" {
+					target += "?" + req.URL.RawQuery
+				}
+				http.Redirect(w, req, target, http.StatusMovedPermanently)
+				return
+			}
+
+			// Serve index file, or a listing if enabled
+			index, err := root.Open(path.Join(name, files.Index))
+			if err == nil {
+				defer index.Close()
+				if indexInfo, err := index.Stat(); err == nil && !indexInfo.IsDir() {
+					http.ServeContent(w, req, indexInfo.Name(), indexInfo.ModTime(), index)
+					return
+				}
+			}
+			if files.Listing {
+				writeListing(w, file, files.Hidden)
+				return
+			}
+
+			http.NotFound(w, req)
+			return
+		}
+
+		http.ServeContent(w, req, info.Name(), info.ModTime(), file)
+	})
+}
+
+// Reports whether a segment of the cleaned path starts with a dot
+func isHidden(name string) bool {
+	return slices.ContainsFunc(strings.Split(name, "/"), func(segment string) bool { return strings.HasPrefix(segment, ".") })
+}
+
+// Write a simple html listing of the directory entries, hidden ones only if enabled
+func writeListing(w http.ResponseWriter, dir http.File, hidden bool) {
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		http.Error(w, "Error reading directory", http.StatusInternalServerError)
+		return
+	}
+	slices.SortFunc(entries, func(a, b os.FileInfo) int { return strings.Compare(a.Name(), b.Name()) })
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintln(w, "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>")
+	for _, entry := range entries {
+		name := entry.Name()
+		if !hidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		link := url.URL{Path: name}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(name))
+	}
+	fmt.Fprintln(w, "</pre>")
+}
+
+// Forward requests to the upstream, with the wildcard part of the route appended to its path
+func proxyHandler(route *OptimizedRoute, logger *slog.Logger) http.HandlerFunc {
+	// Upstream is validated on config check
+	upstream, _ := url.Parse(route.Proxy.Upstream)
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Path = "/" + chi.URLParam(pr.In, "*")
+			pr.Out.URL.RawPath = ""
+			pr.SetURL(upstream)
+			pr.SetXForwarded()
+			if spanContext := tracing.SpanFromContext(pr.In.Context()).Context(); spanContext.IsValid() {
+				pr.Out.Header.Set("traceparent", spanContext.Traceparent())
+			}
+			if route.Proxy.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			// Credentials checked by webcmd are not meant for the upstream
+			if len(route.Auth) > 0 && !route.Proxy.ForwardAuth {
+				pr.Out.Header.Del("Authorization")
+			}
+
+			for header, value := range route.Proxy.Headers {
+				if value == "" {
+					pr.Out.Header.Del(header)
+				} else {
+					pr.Out.Header.Set(header, value)
+				}
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// Route headers are already set and take precedence over upstream headers
+			for header := range route.Headers {
+				resp.Header.Del(header)
+			}
+			resp.Header.Del("Server")
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			logger.ErrorContext(req.Context(),
+				"error while proxying request",
+				slog.String("error", err.Error()),
+				slog.String("route", route.Route.Route),
+			)
+			writeError(w, req, &route.Route, Problem{Type: ProblemUpstreamFailed, Status: http.StatusBadGateway, Detail: "The upstream server could not be reached"})
+		},
+	}
+
+	return proxy.ServeHTTP
+}
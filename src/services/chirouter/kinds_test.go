@@ -0,0 +1,237 @@
+package chirouter
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKinds(t *testing.T) {
+	// Files
+	directory := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(directory, "docs", "empty"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "index.html"), []byte("index"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "docs", "readme.txt"), []byte("readme"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(directory, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, ".git", "config"), []byte("git"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "docs", ".env"), []byte("env"), 0o644))
+
+	// Upstream echoing the request path and a header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Upstream", "true")
+		w.Write([]byte(req.URL.Path + " " + req.Header.Get("X-Token") + " " + req.Header.Get("Authorization")))
+	}))
+	defer upstream.Close()
+
+	static := testRoute("/static", "")
+	static.Static = &config.RouteStatic{StatusCode: http.StatusAccepted, Body: "static body"}
+	static.Headers = map[string]string{"Content-Type": "text/plain"}
+	redirect := testRoute("/users/{id}", "")
+	redirect.Redirect = &config.RouteRedirect{Location: "/profiles/${WC_ID}"}
+	redirect.Parameters = []config.RouteParameter{{Source: config.ParamSourceRoute, Name: "id"}}
+	next := testRoute("/next", config.ErrorFormatProblem)
+	next.Redirect = &config.RouteRedirect{Location: "/${WC_NEXT}"}
+	next.Parameters = []config.RouteParameter{{Source: config.ParamSourceQuery, Name: "next"}}
+	files := testRoute("/files/*", "")
+	files.Files = &config.RouteFiles{Directory: directory}
+	listing := testRoute("/listing/*", "")
+	listing.Files = &config.RouteFiles{Directory: directory, Index: "missing", Listing: true}
+	hidden := testRoute("/hidden/*", "")
+	hidden.Files = &config.RouteFiles{Directory: directory, Index: "missing", Listing: true, Hidden: true}
+	proxy := testRoute("/proxy/*", "")
+	proxy.Proxy = &config.RouteProxy{Upstream: upstream.URL + "/base", Headers: map[string]string{"X-Token": "secret", "Authorization": ""}}
+	brokenProxy := testRoute("/broken/*", config.ErrorFormatProblem)
+	brokenProxy.Proxy = &config.RouteProxy{Upstream: "http://127.0.0.1:1"}
+
+	server := httptest.NewServer(testHandler(t, &fakeExecuter{}, static, redirect, next, files, listing, hidden, proxy, brokenProxy))
+	defer server.Close()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }}
+
+	testCases := []struct {
+		Name               string
+		Path               string
+		ExpectedStatusCode int
+		ExpectedBody       string
+		ExpectedHeaders    map[string]string
+	}{
+		{Name: "static", Path: "/static", ExpectedStatusCode: 202, ExpectedBody: "static body", ExpectedHeaders: map[string]string{"Content-Type": "text/plain"}},
+		{Name: "redirect", Path: "/users/42", ExpectedStatusCode: 302, ExpectedHeaders: map[string]string{"Location": "/profiles/42"}},
+		{Name: "redirect on host", Path: "/next?next=home", ExpectedStatusCode: 302, ExpectedHeaders: map[string]string{"Location": "/home"}},
+		{Name: "redirect to other host", Path: "/next?next=/evil.example", ExpectedStatusCode: 400, ExpectedHeaders: map[string]string{"Content-Type": ProblemContentType}},
+		{Name: "redirect to other host with backslash", Path: "/next?next=%5Cevil.example", ExpectedStatusCode: 400},
+		{Name: "file", Path: "/files/docs/readme.txt", ExpectedStatusCode: 200, ExpectedBody: "readme"},
+		{Name: "index", Path: "/files/", ExpectedStatusCode: 200, ExpectedBody: "index"},
+		{Name: "directory without slash", Path: "/files/docs", ExpectedStatusCode: 301, ExpectedHeaders: map[string]string{"Location": "/files/docs/"}},
+		{Name: "directory without index", Path: "/files/docs/", ExpectedStatusCode: 404},
+		{Name: "missing file", Path: "/files/missing.txt", ExpectedStatusCode: 404},
+		{Name: "traversal", Path: "/files/../../etc/passwd", ExpectedStatusCode: 404},
+		{Name: "hidden file", Path: "/files/docs/.env", ExpectedStatusCode: 404},
+		{Name: "hidden directory", Path: "/files/.git/config", ExpectedStatusCode: 404},
+		{Name: "hidden file enabled", Path: "/hidden/.git/config", ExpectedStatusCode: 200, ExpectedBody: "git"},
+		{Name: "hidden listing", Path: "/hidden/docs/", ExpectedStatusCode: 200, ExpectedBody: "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n<a href=\".env\">.env</a>\n<a href=\"empty/\">empty/</a>\n<a href=\"readme.txt\">readme.txt</a>\n</pre>\n"},
+		{Name: "listing", Path: "/listing/docs/", ExpectedStatusCode: 200, ExpectedBody: "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n<a href=\"empty/\">empty/</a>\n<a href=\"readme.txt\">readme.txt</a>\n</pre>\n"},
+		{Name: "proxy", Path: "/proxy/items/1", ExpectedStatusCode: 200, ExpectedBody: "/base/items/1 secret ", ExpectedHeaders: map[string]string{"X-Upstream": "true"}},
+		{Name: "proxy unavailable", Path: "/broken/", ExpectedStatusCode: 502, ExpectedHeaders: map[string]string{"Content-Type": ProblemContentType}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+tc.Path, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Basic dGVzdDp0ZXN0")
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ExpectedStatusCode, resp.StatusCode)
+			if tc.ExpectedBody != "" {
+				assert.Equal(t, tc.ExpectedBody, string(body))
+			}
+			for header, value := range tc.ExpectedHeaders {
+				assert.Equal(t, value, resp.Header.Get(header))
+			}
+		})
+	}
+}
+
+func TestProxyAuth(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Header.Get("Authorization")))
+	}))
+	defer upstream.Close()
+
+	authenticators := auth.Collection{"token": &fakeAuthenticator{header: "Authorization", value: "secret"}}
+	protected := testRoute("/protected/*", "")
+	protected.Auth = []string{"token"}
+	protected.Proxy = &config.RouteProxy{Upstream: upstream.URL}
+	forwarding := testRoute("/forwarding/*", "")
+	forwarding.Auth = []string{"token"}
+	forwarding.Proxy = &config.RouteProxy{Upstream: upstream.URL, ForwardAuth: true}
+	public := testRoute("/public/*", "")
+	public.Proxy = &config.RouteProxy{Upstream: upstream.URL}
+	routes := []config.Route{protected, forwarding, public}
+	for i := range routes {
+		routes[i].Check()
+	}
+
+	router := New(Options{Executers: &execution.ExecuterCollection{}, Modules: &config.ModulesConfig{}, Authenticators: authenticators})
+	require.NoError(t, router.Register(context.Background(), routes, nil))
+	server := httptest.NewServer(router.Handler())
+	defer server.Close()
+
+	testCases := []struct {
+		Name         string
+		Path         string
+		ExpectedBody string
+	}{
+		{Name: "removed on routes with auth", Path: "/protected/", ExpectedBody: ""},
+		{Name: "forwarded with forwardAuth", Path: "/forwarding/", ExpectedBody: "secret"},
+		{Name: "forwarded without auth", Path: "/public/", ExpectedBody: "secret"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+tc.Path, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "secret")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tc.ExpectedBody, string(body))
+		})
+	}
+}
+
+func TestProxyUpgrade(t *testing.T) {
+	// Upstream switching to a line echo protocol
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Upgrade") != "echo" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, buffer, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		buffer.Flush()
+		line, _ := buffer.ReadString('\n')
+		buffer.WriteString(line)
+		buffer.Flush()
+	}))
+	defer upstream.Close()
+
+	route := testRoute("/upgrade/*", "")
+	route.Proxy = &config.RouteProxy{Upstream: upstream.URL}
+	server := httptest.NewServer(testHandler(t, &fakeExecuter{}, route))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write([]byte("GET /upgrade/ HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	_, err = conn.Write([]byte("ping\n"))
+	require.NoError(t, err)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "ping\n", line)
+}
+
+func TestProxyStreaming(t *testing.T) {
+	// Upstream sending an event, and the next only after the first was received
+	received := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-received:
+		case <-req.Context().Done():
+			return
+		}
+		w.Write([]byte("data: second\n\n"))
+	}))
+	defer upstream.Close()
+	defer close(received)
+
+	route := testRoute("/events/*", "")
+	route.Proxy = &config.RouteProxy{Upstream: upstream.URL}
+	server := httptest.NewServer(testHandler(t, &fakeExecuter{}, route))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(server.URL + "/events/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: first\n", line)
+}
@@ -0,0 +1,19 @@
+package chirouter
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/bdoerfchen/webcmd/src/services/server"
+)
+
+// Answer requests received on other listeners than the route's with 404 Not Found, as if the route did not exist
+func listenerFilter(listeners []string, handler http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !slices.Contains(listeners, server.ListenerFromContext(req.Context())) {
+			http.NotFound(w, req)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
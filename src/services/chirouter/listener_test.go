@@ -0,0 +1,118 @@
+package chirouter
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerFilter(t *testing.T) {
+	internal := testRoute("/internal", "")
+	internal.Method = http.MethodPost
+	internal.Listeners = []string{"internal"}
+	internal.CORS = &config.CORSConfig{AllowedOrigins: []string{"*"}}
+	mixed := testRoute("/mixed", "")
+	mixedInternal := testRoute("/mixed", "")
+	mixedInternal.Method = http.MethodDelete
+	mixedInternal.Listeners = []string{"internal"}
+	routes := []config.Route{internal, testRoute("/public", ""), mixed, mixedInternal}
+
+	var executers execution.ExecuterCollection
+	executers.Add(&fakeExecuter{stdout: "ok"})
+	for i := range routes {
+		routes[i].Exec.Proc = &config.ExecProc{Path: "test"}
+		routes[i].Check()
+	}
+	router := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}})
+	require.NoError(t, router.Register(context.Background(), routes, nil))
+	handler := router.Handler()
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		Headers            map[string]string
+		Listener           string
+		ExpectedStatusCode int
+		ExpectedAllow      string
+	}{
+		{Name: "route of listener", Method: http.MethodPost, Path: "/internal", Listener: "internal", ExpectedStatusCode: 200},
+		{Name: "route of other listener", Method: http.MethodPost, Path: "/internal", Listener: "public", ExpectedStatusCode: 404},
+		{Name: "default listener", Method: http.MethodPost, Path: "/internal", Listener: "", ExpectedStatusCode: 404},
+		{Name: "route of all listeners", Method: http.MethodGet, Path: "/public", Listener: "internal", ExpectedStatusCode: 200},
+		{Name: "options of listener", Method: http.MethodOptions, Path: "/internal", Listener: "internal", ExpectedStatusCode: 204, ExpectedAllow: "OPTIONS, POST"},
+		{Name: "options of other listener", Method: http.MethodOptions, Path: "/internal", Listener: "public", ExpectedStatusCode: 404},
+		{Name: "method not allowed on other listener", Method: http.MethodGet, Path: "/internal", Listener: "public", ExpectedStatusCode: 404},
+		{Name: "preflight of other listener", Method: http.MethodOptions, Path: "/internal", Headers: map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "POST"}, Listener: "public", ExpectedStatusCode: 404},
+		{Name: "options without methods of other listener", Method: http.MethodOptions, Path: "/mixed", Listener: "public", ExpectedStatusCode: 204, ExpectedAllow: "GET, HEAD, OPTIONS"},
+		{Name: "options with methods of listener", Method: http.MethodOptions, Path: "/mixed", Listener: "internal", ExpectedStatusCode: 204, ExpectedAllow: "DELETE, GET, HEAD, OPTIONS"},
+		{Name: "method not allowed without methods of other listener", Method: http.MethodPut, Path: "/mixed", Listener: "public", ExpectedStatusCode: 405, ExpectedAllow: "GET, HEAD, OPTIONS"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.Method, tc.Path, nil)
+			for header, value := range tc.Headers {
+				req.Header.Set(header, value)
+			}
+			req = req.WithContext(server.WithListener(req.Context(), tc.Listener))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.ExpectedStatusCode, recorder.Code)
+			assert.Equal(t, tc.ExpectedAllow, recorder.Header().Get("Allow"))
+			assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestListenerOpenAPI(t *testing.T) {
+	internal := testRoute("/internal", "")
+	internal.Listeners = []string{"internal"}
+	routes := []config.Route{internal, testRoute("/public", "")}
+	modules := &config.ModulesConfig{OpenAPI: &config.OpenAPIConfig{Path: "/openapi.json"}}
+	modules.OpenAPI.Check()
+
+	var executers execution.ExecuterCollection
+	executers.Add(&fakeExecuter{})
+	for i := range routes {
+		routes[i].Exec.Proc = &config.ExecProc{Path: "test"}
+		routes[i].Check()
+	}
+	router := New(Options{Executers: &executers, Modules: modules})
+	require.NoError(t, router.Register(context.Background(), routes, nil))
+	handler := router.Handler()
+
+	testCases := []struct {
+		Name          string
+		Listener      string
+		ExpectedPaths []string
+	}{
+		{Name: "routes of listener", Listener: "internal", ExpectedPaths: []string{"/internal", "/public"}},
+		{Name: "routes of other listener left out", Listener: "public", ExpectedPaths: []string{"/public"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+			req = req.WithContext(server.WithListener(req.Context(), tc.Listener))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var document struct{ Paths map[string]any }
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &document))
+			assert.ElementsMatch(t, tc.ExpectedPaths, slices.Collect(maps.Keys(document.Paths)))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package chirouter
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/cacher"
+	"github.com/bdoerfchen/webcmd/src/common/metrics"
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware counting finished requests by their route pattern. The pattern is empty for requests without matching route
+func (r *chirouter) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		startTime := time.Now()
+		responseTracker := &trackingResponseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(responseTracker, req)
+
+		// The route context is filled by the router while routing
+		var pattern string
+		if routeContext := chi.RouteContext(req.Context()); routeContext != nil {
+			pattern = routeContext.RoutePattern()
+		}
+		r.metrics.ObserveRequest(pattern, methodLabel(req.Method), responseTracker.StatusCode(), time.Since(startTime))
+	})
+}
+
+// Label of the request method. Clients can send any method, so unknown ones share a label to keep the number of series bounded
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+type cacheMissKey struct{}
+
+// Wrap the handler in the cacher and count whether requests are answered from cache, which is the case if the handler is not called
+func cacheObserver(cacher cacher.Cacher, route string, recorder metrics.Recorder, handler http.HandlerFunc) http.HandlerFunc {
+	cached := cacher.Cache(route, func(w http.ResponseWriter, req *http.Request) {
+		if missed, ok := req.Context().Value(cacheMissKey{}).(*bool); ok {
+			*missed = true
+		}
+		handler(w, req)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		missed := false
+		cached(w, req.WithContext(context.WithValue(req.Context(), cacheMissKey{}, &missed)))
+		recorder.ObserveCache(!missed)
+	})
+}
@@ -0,0 +1,55 @@
+package chirouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Recorder remembering the method labels of observed requests
+type fakeRecorder struct {
+	metrics.Recorder
+	methods []string
+}
+
+func (r *fakeRecorder) ObserveRequest(route, method string, status int, duration time.Duration) {
+	r.methods = append(r.methods, method)
+}
+
+func TestMetricsMethods(t *testing.T) {
+	var executers execution.ExecuterCollection
+	executers.Add(&fakeExecuter{})
+	route := testRoute("/", "")
+	route.Exec.Proc = &config.ExecProc{Path: "test"}
+	route.Check()
+	recorder := &fakeRecorder{Recorder: metrics.Discard}
+	router := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}, Recorder: recorder})
+	require.NoError(t, router.Register(context.Background(), []config.Route{route}, nil))
+
+	testCases := []struct {
+		Name           string
+		Method         string
+		ExpectedMethod string
+	}{
+		{Name: "standard method", Method: http.MethodGet, ExpectedMethod: http.MethodGet},
+		{Name: "not allowed standard method", Method: http.MethodDelete, ExpectedMethod: http.MethodDelete},
+		{Name: "unknown method", Method: "FOOBAR", ExpectedMethod: "OTHER"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			recorder.methods = nil
+			router.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.Method, "/", nil))
+
+			assert.Equal(t, []string{tc.ExpectedMethod}, recorder.methods)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package chirouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/services/openapidoc"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+)
+
+// Serve the OpenAPI document of the routes served on the listener of the request. Each listener's document is generated once
+func openAPIHandler(routes []config.Route, groups []config.RouteGroup, modules *config.ModulesConfig) (http.HandlerFunc, error) {
+	var documents sync.Map
+	generate := func(listener string) ([]byte, error) {
+		if document, ok := documents.Load(listener); ok {
+			return document.([]byte), nil
+		}
+
+		listenerGroups := make([]config.RouteGroup, len(groups))
+		for i, group := range groups {
+			listenerGroups[i] = group
+			listenerGroups[i].Routes = servedRoutes(group.Routes, listener)
+		}
+		document, err := json.Marshal(openapidoc.Generate(servedRoutes(routes, listener), listenerGroups, modules))
+		if err != nil {
+			return nil, err
+		}
+		documents.Store(listener, document)
+		return document, nil
+	}
+
+	// Generate the document of the default listener right away, so errors are reported on registration
+	if _, err := generate(""); err != nil {
+		return nil, err
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Server", ServerHeader)
+		document, err := generate(server.ListenerFromContext(req.Context()))
+		if err != nil {
+			http.Error(w, "The OpenAPI document could not be generated", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document)
+	}, nil
+}
+
+// Returns the routes served on the listener, leaving out those of other listeners
+func servedRoutes(routes []config.Route, listener string) []config.Route {
+	return slices.DeleteFunc(slices.Clone(routes), func(route config.Route) bool {
+		return len(route.Listeners) > 0 && !slices.Contains(route.Listeners, listener)
+	})
+}
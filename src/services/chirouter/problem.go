@@ -0,0 +1,66 @@
+package chirouter
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/params"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem types used in problem details responses
+const (
+	ProblemExecutionFailed  = "urn:webcmd:problem:execution-failed"
+	ProblemExecutionTimeout = "urn:webcmd:problem:execution-timeout"
+	ProblemMappedExitCode   = "urn:webcmd:problem:exit-code"
+)
+
+// Problem details as defined in RFC 9457, with webcmd specific extension members
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Route     string `json:"route,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	ExitCode  *int   `json:"exitCode,omitempty"`
+}
+
+// Write an error response for a route. Uses problem details if configured for the route, otherwise only the status code is written
+func writeError(w http.ResponseWriter, req *http.Request, route *config.Route, problem Problem) {
+	if route == nil || route.ErrorFormat != config.ErrorFormatProblem {
+		w.WriteHeader(problem.Status)
+		return
+	}
+
+	writeProblem(w, req, route, problem)
+}
+
+// Write problem details, completing the request specific members
+func writeProblem(w http.ResponseWriter, req *http.Request, route *config.Route, problem Problem) {
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	problem.Instance = req.URL.Path
+	problem.RequestID = params.EnvFromContext(req.Context())[EnvRequestID]
+	if route != nil {
+		problem.Route = route.String()
+	}
+
+	content, err := json.Marshal(problem)
+	if err != nil {
+		w.WriteHeader(problem.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(problem.Status)
+	w.Write(content)
+}
@@ -0,0 +1,76 @@
+package chirouter
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/ratelimit"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+)
+
+const ProblemRateLimited = "urn:webcmd:problem:rate-limited"
+
+// Wrap a route handler to reject requests exceeding the limit. Only one limiter of a route reports its state in the RateLimit headers
+func rateLimitHandler(limiter ratelimit.Limiter, limit *config.RateLimit, route *OptimizedRoute, handler http.HandlerFunc, reportHeaders bool, logger *slog.Logger) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		result := limiter.Allow(rateLimitKey(limit, route, req))
+
+		if !result.Allowed {
+			logger.DebugContext(req.Context(),
+				"rate limit exceeded",
+				slog.String("key", string(limit.Key)),
+				slog.String("route", route.Route.Route),
+			)
+			setRateLimitHeaders(w.Header(), result)
+			w.Header().Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+			w.Header().Add("Server", ServerHeader)
+			writeError(w, req, &route.Route, Problem{Type: ProblemRateLimited, Status: http.StatusTooManyRequests, Detail: "Too many requests, retry later"})
+			return
+		}
+
+		if !reportHeaders {
+			handler(w, req)
+			return
+		}
+		// Headers are set on write, as cached responses would replay outdated ones
+		handler(&headerHookWriter{ResponseWriter: w, hook: func(header http.Header) {
+			setRateLimitHeaders(header, result)
+		}}, req)
+	})
+}
+
+// Returns the key the request is counted by. Falls back to the client IP if the principal or parameter is missing
+func rateLimitKey(limit *config.RateLimit, route *OptimizedRoute, req *http.Request) string {
+	switch limit.Key {
+	case config.RateLimitKeyPrincipal:
+		if principal := auth.PrincipalFromContext(req.Context()); principal != nil {
+			return "principal:" + principal.Authenticator + ":" + principal.Name
+		}
+	case config.RateLimitKeyParameter:
+		if value := route.parameters.For(req)[limit.Parameter]; value != "" {
+			return "parameter:" + value
+		}
+	}
+
+	if addr, ok := remoteAddr(req); ok {
+		return "ip:" + addr.String()
+	}
+	// Clients without address, like those of unix sockets, can not be told apart and share the key of their listener
+	return "listener:" + server.ListenerFromContext(req.Context()) + ":" + req.RemoteAddr
+}
+
+func setRateLimitHeaders(header http.Header, result ratelimit.Result) {
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+}
+
+// Round up to whole seconds
+func seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
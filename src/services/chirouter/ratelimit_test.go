@@ -0,0 +1,66 @@
+package chirouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	limited := testRoute("/limited/{tenant}", config.ErrorFormatProblem)
+	limited.Parameters = []config.RouteParameter{{Source: config.ParamSourceRoute, Name: "tenant", As: "TENANT"}}
+	limited.RateLimit = &config.RateLimit{Requests: 1, Period: timem.Duration(time.Hour), Key: config.RateLimitKeyParameter, Parameter: "TENANT"}
+	routes := []config.Route{limited, testRoute("/global", "")}
+	modules := &config.ModulesConfig{RateLimit: &config.RateLimit{Requests: 2, Period: timem.Duration(time.Hour)}}
+	modules.RateLimit.Check(true)
+
+	var executers execution.ExecuterCollection
+	executers.Add(&fakeExecuter{stdout: "ok"})
+	for i := range routes {
+		routes[i].Exec.Proc = &config.ExecProc{Path: "test"}
+		routes[i].Check()
+	}
+	router := New(Options{Executers: &executers, Modules: modules})
+	require.NoError(t, router.Register(context.Background(), routes, nil))
+	handler := router.Handler()
+
+	testCases := []struct {
+		Name               string
+		Path               string
+		RemoteAddr         string
+		ExpectedStatusCode int
+		ExpectedRemaining  string
+		ExpectedRetryAfter string
+	}{
+		{Name: "global first", Path: "/global", RemoteAddr: "192.0.2.1:1000", ExpectedStatusCode: 200, ExpectedRemaining: "1"},
+		{Name: "global second", Path: "/global", RemoteAddr: "192.0.2.1:1001", ExpectedStatusCode: 200, ExpectedRemaining: "0"},
+		{Name: "global exceeded", Path: "/global", RemoteAddr: "192.0.2.1:1002", ExpectedStatusCode: 429, ExpectedRemaining: "0", ExpectedRetryAfter: "1800"},
+		{Name: "global other ip", Path: "/global", RemoteAddr: "192.0.2.2:1000", ExpectedStatusCode: 200, ExpectedRemaining: "1"},
+		{Name: "global client without address", Path: "/global", RemoteAddr: "", ExpectedStatusCode: 200, ExpectedRemaining: "1"},
+		{Name: "route reports its limit", Path: "/limited/a", RemoteAddr: "192.0.2.3:1000", ExpectedStatusCode: 200, ExpectedRemaining: "0"},
+		{Name: "route exceeded", Path: "/limited/a", RemoteAddr: "192.0.2.3:1000", ExpectedStatusCode: 429, ExpectedRemaining: "0", ExpectedRetryAfter: "3600"},
+		{Name: "global exceeded on route", Path: "/limited/b", RemoteAddr: "192.0.2.3:1000", ExpectedStatusCode: 429, ExpectedRemaining: "0", ExpectedRetryAfter: "1800"},
+		{Name: "route other parameter", Path: "/limited/b", RemoteAddr: "192.0.2.4:1000", ExpectedStatusCode: 200, ExpectedRemaining: "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			req.RemoteAddr = tc.RemoteAddr
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.ExpectedStatusCode, recorder.Code)
+			assert.Equal(t, tc.ExpectedRemaining, recorder.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, tc.ExpectedRetryAfter, recorder.Header().Get("Retry-After"))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package chirouter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/params"
+	"github.com/bdoerfchen/webcmd/src/logging"
+)
+
+// Env variable exposing the request ID to commands
+const EnvRequestID = config.RouteParamPrefix + "REQUEST_ID"
+
+// Request IDs sent by clients are only accepted if they are safe to use in logs and env variables
+var validRequestID = regexp.MustCompile(`^[\w.:+/=-]{1,128}$`)
+
+// Middleware accepting the request ID of the client or generating one. It is added to the logger and env variables of the request, and returned in the response
+func requestIDMiddleware(header string, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(header)
+			if !validRequestID.MatchString(id) {
+				id = newRequestID()
+			}
+
+			ctx := params.AddEnvToContext(req.Context(), params.EnvMap{EnvRequestID: id})
+			ctx = logging.AddToContext(ctx, logger.With(slog.String("requestId", id)))
+
+			// Set on write, as cached responses would replay the ID of the cached request
+			next.ServeHTTP(&headerHookWriter{ResponseWriter: w, hook: func(h http.Header) {
+				h.Set(header, id)
+			}}, req.WithContext(ctx))
+		})
+	}
+}
+
+func newRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
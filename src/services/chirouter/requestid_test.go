@@ -0,0 +1,58 @@
+package chirouter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	handler := testHandler(t, &fakeExecuter{echoEnv: EnvRequestID}, testRoute("/id", ""))
+
+	testCases := []struct {
+		Name       string
+		RequestID  string
+		ExpectedID string // Empty if a new ID is generated
+	}{
+		{Name: "accepted", RequestID: "abc-123", ExpectedID: "abc-123"},
+		{Name: "generated"},
+		{Name: "unsafe replaced", RequestID: "$(reboot)"},
+		{Name: "too long replaced", RequestID: string(make([]byte, 129))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/id", nil)
+			req.Header.Set("X-Request-Id", tc.RequestID)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			id := recorder.Header().Get("X-Request-Id")
+			assert.Equal(t, id, recorder.Body.String(), "command gets the returned id")
+			if tc.ExpectedID != "" {
+				assert.Equal(t, tc.ExpectedID, id)
+			} else {
+				assert.Len(t, id, 32)
+			}
+		})
+	}
+}
+
+func TestRequestIDInProblem(t *testing.T) {
+	handler := testHandler(t, &fakeExecuter{err: errors.New("broken")}, testRoute("/problem", config.ErrorFormatProblem))
+
+	req := httptest.NewRequest(http.MethodGet, "/problem", nil)
+	req.Header.Set("X-Request-Id", "abc-123")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, "abc-123", problem.RequestID)
+}
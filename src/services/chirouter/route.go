@@ -3,6 +3,7 @@ package chirouter
 import (
 	"bytes"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/bdoerfchen/webcmd/src/common/config"
@@ -11,29 +12,26 @@ import (
 	"github.com/bdoerfchen/webcmd/src/services/paramcollection"
 )
 
-const DefaultKey = -1
-
 type OptimizedRoute struct {
 	config.Route
-	StatusCodeMap map[int]OptimizedMapping
-	parameters    params.ParameterProvider
+	StatusCodeMatchers []OptimizedMapping // Mappings with conditions, matched in order
+	DefaultMapping     OptimizedMapping   // Mapping used when no matcher applies
+	parameters         params.ParameterProvider
 }
 
 type OptimizedMapping struct {
 	config.ExitCodeMapping
+	signal string
+	stdout *regexp.Regexp
+	stderr *regexp.Regexp
 }
 
 func OptimizeRoute(route config.Route) (result OptimizedRoute) {
 	result.Route = route
-	result.StatusCodeMap = make(map[int]OptimizedMapping)
+	var defaultMapping *OptimizedMapping
 
-	// Convert all mappings and add them to map
+	// Convert all mappings and add them in order
 	for _, codeMap := range route.StatusCodes {
-		key := DefaultKey
-		if codeMap.ExitCode != nil {
-			key = *codeMap.ExitCode
-		}
-
 		if codeMap.StatusCode < http.StatusOK {
 			// Server should not answer below 200 when request is finished
 			codeMap.StatusCode = http.StatusOK
@@ -48,14 +46,31 @@ func OptimizeRoute(route config.Route) (result OptimizedRoute) {
 			codeMap.ResponseStream = route.ResponseStream
 		}
 
-		// Add entry
-		result.StatusCodeMap[key] = OptimizedMapping{codeMap}
+		// Compile conditions. Invalid patterns are detected on config check
+		mapping := OptimizedMapping{ExitCodeMapping: codeMap, signal: process.NormalizeSignalName(codeMap.Signal)}
+		if codeMap.Stdout != "" {
+			mapping.stdout, _ = regexp.Compile(codeMap.Stdout)
+		}
+		if codeMap.Stderr != "" {
+			mapping.stderr, _ = regexp.Compile(codeMap.Stderr)
+		}
+
+		// The first mapping without conditions is the default
+		if codeMap.IsDefault() {
+			if defaultMapping == nil {
+				defaultMapping = &mapping
+			}
+			continue
+		}
+		result.StatusCodeMatchers = append(result.StatusCodeMatchers, mapping)
 	}
 
 	// Add defaults
-	if _, ok := result.StatusCodeMap[DefaultKey]; !ok {
-		result.StatusCodeMap[DefaultKey] = OptimizedMapping{
-			config.ExitCodeMapping{
+	if defaultMapping != nil {
+		result.DefaultMapping = *defaultMapping
+	} else {
+		result.DefaultMapping = OptimizedMapping{
+			ExitCodeMapping: config.ExitCodeMapping{
 				StatusCode:     http.StatusInternalServerError,
 				ResponseStream: config.Both,
 			},
@@ -68,12 +83,36 @@ func OptimizeRoute(route config.Route) (result OptimizedRoute) {
 	return
 }
 
-func (o *OptimizedRoute) ExitCodeResponse(code int) OptimizedMapping {
-	if response, ok := o.StatusCodeMap[code]; ok {
-		return response
+// Returns the first mapping matching the execution result, or the default mapping
+func (o *OptimizedRoute) ExitCodeResponse(code int, proc *process.Process) OptimizedMapping {
+	for _, mapping := range o.StatusCodeMatchers {
+		if mapping.Matches(code, proc) {
+			return mapping
+		}
+	}
+
+	return o.DefaultMapping
+}
+
+// Returns true if all conditions of the mapping match the execution result
+func (o *OptimizedMapping) Matches(code int, proc *process.Process) bool {
+	if o.ExitCode != nil && *o.ExitCode != code {
+		return false
+	}
+	if o.ExitCodes != nil && !o.ExitCodes.Contains(code) {
+		return false
+	}
+	if o.signal != "" && (proc == nil || proc.Signal != o.signal) {
+		return false
+	}
+	if o.stdout != nil && (proc == nil || !o.stdout.Match(proc.StdOut.Bytes())) {
+		return false
+	}
+	if o.stderr != nil && (proc == nil || !o.stderr.Match(proc.StdErr.Bytes())) {
+		return false
 	}
 
-	return o.StatusCodeMap[DefaultKey]
+	return true
 }
 
 func (o *OptimizedMapping) ResponseBufferFor(proc *process.Process) *bytes.Buffer {
@@ -0,0 +1,80 @@
+package chirouter
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/process"
+	"github.com/stretchr/testify/assert"
+)
+
+func exitCode(code int) *int {
+	return &code
+}
+
+func exitCodes(from, to int) *config.ExitCodeRange {
+	return &config.ExitCodeRange{From: from, To: to}
+}
+
+func TestExitCodeResponse(t *testing.T) {
+	mappings := []config.ExitCodeMapping{
+		{Stderr: "^permission denied", StatusCode: http.StatusForbidden},
+		{ExitCode: exitCode(0), StatusCode: http.StatusOK},
+		{ExitCode: exitCode(12), StatusCode: http.StatusConflict},
+		{ExitCodes: exitCodes(10, 19), StatusCode: http.StatusBadRequest},
+		{ExitCodes: exitCodes(10, 29), Stdout: "missing", StatusCode: http.StatusNotFound},
+		{Signal: "kill", StatusCode: http.StatusGatewayTimeout},
+		{StatusCode: http.StatusServiceUnavailable},
+		{StatusCode: http.StatusTeapot},
+	}
+
+	testCases := []struct {
+		Name               string
+		ExitCode           int
+		Signal             string
+		Stdout             string
+		Stderr             string
+		ExpectedStatusCode int
+	}{
+		{Name: "exact", ExitCode: 0, ExpectedStatusCode: http.StatusOK},
+		{Name: "exact before range", ExitCode: 12, ExpectedStatusCode: http.StatusConflict},
+		{Name: "range", ExitCode: 15, ExpectedStatusCode: http.StatusBadRequest},
+		{Name: "range lower bound", ExitCode: 10, ExpectedStatusCode: http.StatusBadRequest},
+		{Name: "range upper bound", ExitCode: 19, ExpectedStatusCode: http.StatusBadRequest},
+		{Name: "earlier range wins over output", ExitCode: 15, Stdout: "file missing", ExpectedStatusCode: http.StatusBadRequest},
+		{Name: "range with output", ExitCode: 25, Stdout: "file missing", ExpectedStatusCode: http.StatusNotFound},
+		{Name: "range without output", ExitCode: 25, ExpectedStatusCode: http.StatusServiceUnavailable},
+		{Name: "output before exit code", ExitCode: 0, Stderr: "permission denied: /etc", ExpectedStatusCode: http.StatusForbidden},
+		{Name: "output anchored", ExitCode: 0, Stderr: "error: permission denied", ExpectedStatusCode: http.StatusOK},
+		{Name: "signal", ExitCode: -1, Signal: "SIGKILL", ExpectedStatusCode: http.StatusGatewayTimeout},
+		{Name: "other signal", ExitCode: -1, Signal: "SIGTERM", ExpectedStatusCode: http.StatusServiceUnavailable},
+		{Name: "first default", ExitCode: 1, ExpectedStatusCode: http.StatusServiceUnavailable},
+	}
+
+	route := OptimizeRoute(config.Route{StatusCodes: mappings})
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			proc := &process.Process{Signal: tc.Signal}
+			proc.StdOut.WriteString(tc.Stdout)
+			proc.StdErr.WriteString(tc.Stderr)
+
+			mapping := route.ExitCodeResponse(tc.ExitCode, proc)
+			assert.Equal(t, tc.ExpectedStatusCode, mapping.StatusCode)
+		})
+	}
+}
+
+func TestExitCodeResponseDefault(t *testing.T) {
+	route := OptimizeRoute(config.Route{
+		StatusCodes:    []config.ExitCodeMapping{{ExitCode: exitCode(0), StatusCode: http.StatusOK}},
+		ResponseStream: config.StdOut,
+	})
+
+	mapping := route.ExitCodeResponse(1, &process.Process{})
+	assert.Equal(t, http.StatusInternalServerError, mapping.StatusCode)
+	assert.Equal(t, config.Both, mapping.ResponseStream)
+
+	mapping = route.ExitCodeResponse(0, &process.Process{})
+	assert.Equal(t, config.StdOut, mapping.ResponseStream)
+}
@@ -1,18 +1,37 @@
 package chirouter
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"os"
+	"reflect"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bdoerfchen/webcmd/src/common/auth"
 	"github.com/bdoerfchen/webcmd/src/common/cacher"
 	"github.com/bdoerfchen/webcmd/src/common/config"
 	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/health"
+	"github.com/bdoerfchen/webcmd/src/common/metrics"
+	"github.com/bdoerfchen/webcmd/src/common/params"
+	"github.com/bdoerfchen/webcmd/src/common/ratelimit"
+	"github.com/bdoerfchen/webcmd/src/common/router"
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
 	"github.com/bdoerfchen/webcmd/src/common/version"
+	"github.com/bdoerfchen/webcmd/src/common/webhook"
 	"github.com/bdoerfchen/webcmd/src/logging"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+	"github.com/bdoerfchen/webcmd/src/services/tokenbucket"
+	"github.com/bdoerfchen/webcmd/src/services/webhookverifier"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -23,13 +42,57 @@ type chirouter struct {
 	router             chi.Router
 	executerCollection *execution.ExecuterCollection
 	cacher             cacher.Cacher
+	authenticators     auth.Collection
+	modules            *config.ModulesConfig
+	globalLimiter      ratelimit.Limiter   // Limiter of the global rate limit shared by all routes, nil if disabled
+	limiters           []ratelimit.Limiter // All limiters of the router, stopped on close
+	metrics            metrics.Recorder
+	tracer             tracing.Tracer
+	health             health.Monitor              // Checks deciding about readiness, nil if there are none
+	patterns           map[string]*patternRoutes   // Registered route patterns by their full pattern
+	routes             map[string]*registeredRoute // Registered routes by their name
+	executions         *execution.Tracker          // Running executions, shared by all routers of the executers
 }
 
-func New(executerCollection *execution.ExecuterCollection, cacher cacher.Cacher) *chirouter {
+// The routes registered for a pattern
+type patternRoutes struct {
+	router    chi.Router                    // Router the pattern is registered with
+	pattern   string                        // Pattern relative to the router
+	methods   []string                      // Registered methods
+	listeners map[string][]string           // Listeners serving the routes by method. Routes without listeners are served on all
+	cors      map[string]*config.CORSConfig // CORS config of the registered routes by method
+}
+
+// Dependencies of a router. Only the executers and modules are required, the others are disabled if nil
+type Options struct {
+	Executers      *execution.ExecuterCollection // Executers of the routes with exec config
+	Modules        *config.ModulesConfig         // Server-wide module config, like CORS, rate limits and address lists
+	Cacher         cacher.Cacher                 // Cache of the routes with caching enabled
+	Authenticators auth.Collection               // Authenticators by their name, as referenced by routes
+	Recorder       metrics.Recorder              // Recorder of request metrics
+	Tracer         tracing.Tracer                // Tracer starting a span for each request
+	Monitor        health.Monitor                // Checks deciding about readiness
+}
+
+func New(options Options) *chirouter {
+	if options.Recorder == nil {
+		options.Recorder = metrics.Discard
+	}
+	if options.Tracer == nil {
+		options.Tracer = tracing.Discard
+	}
 	return &chirouter{
 		router:             chi.NewRouter(),
-		executerCollection: executerCollection,
-		cacher:             cacher,
+		executerCollection: options.Executers,
+		cacher:             options.Cacher,
+		authenticators:     options.Authenticators,
+		modules:            options.Modules,
+		metrics:            options.Recorder,
+		tracer:             options.Tracer,
+		health:             options.Monitor,
+		patterns:           make(map[string]*patternRoutes),
+		routes:             make(map[string]*registeredRoute),
+		executions:         options.Executers.Running(),
 	}
 }
 
@@ -37,103 +100,451 @@ func (r *chirouter) Handler() http.Handler {
 	return r.router
 }
 
-func (r *chirouter) Register(ctx context.Context, routes []config.Route) error {
+func (r *chirouter) Inherit(previous router.Router) {
+	other, ok := previous.(*chirouter)
+	if !ok {
+		return
+	}
+
+	for name, registered := range r.routes {
+		old, ok := other.routes[name]
+		if !ok {
+			continue
+		}
+		registered.maintenance.Store(old.maintenance.Load())
+		if registered.verifier != nil && old.verifier != nil {
+			registered.verifier.Inherit(old.verifier)
+		}
+		// The shared cache keeps the responses of unchanged routes only
+		if r.cacher != nil && !reflect.DeepEqual(old.route.Route, registered.route.Route) {
+			r.cacher.Purge(name)
+		}
+	}
+	if r.cacher != nil {
+		for name := range other.routes {
+			if _, ok := r.routes[name]; !ok {
+				r.cacher.Purge(name)
+			}
+		}
+	}
+}
+
+func (r *chirouter) Close() {
+	for _, limiter := range r.limiters {
+		limiter.Stop()
+	}
+}
+
+func (r *chirouter) Register(ctx context.Context, routes []config.Route, groups []config.RouteGroup) (err error) {
+	// Conflicting patterns make chi panic. They are reported on config check, but must not end a running server on reload
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("failed to register routes: %v", recovered)
+		}
+	}()
+
 	// Setup logger
 	logger := logging.FromContext(ctx)
-	logger.Debug("begin route registration", slog.Int("count", len(routes)))
+	logger.Debug("begin route registration", slog.Int("count", len(routes)), slog.Int("groups", len(groups)))
+
+	// Address lists are validated on config check
+	trustedProxies, _ := config.ParsePrefixes(r.modules.TrustedProxies)
+	allowFrom, _ := config.ParsePrefixes(r.modules.AllowFrom)
+	denyFrom, _ := config.ParsePrefixes(r.modules.DenyFrom)
 
 	// Basic middleware registration
 	r.router.Use(
 		middleware.StripSlashes,
-		middleware.RealIP,
+		requestIDMiddleware(cmp.Or(r.modules.RequestID.Header, config.DefaultRequestIDHeader), logger), // Identify the request in logs, env and response
+		clientIPMiddleware(trustedProxies), // Use the client address forwarded by trusted proxies
+		r.tracingMiddleware,                // Start a server span, continuing the trace of the client
 		middleware.Recoverer,
-		AccessLogMiddleware(logger), // Custom middleware for logging requests and their responses
+		AccessLogMiddleware(logger, r.unloggedPaths()...), // Custom middleware for logging requests and their responses
+		r.metricsMiddleware,                               // Count requests by their route pattern
 	)
+	if len(allowFrom) > 0 || len(denyFrom) > 0 {
+		r.router.Use(func(next http.Handler) http.Handler {
+			return addressFilter(allowFrom, denyFrom, nil, next)
+		})
+	}
+	r.router.Use(
+		r.corsPreflightMiddleware, // Answer CORS preflight requests without executing routes
+		clientCertMiddleware,      // Expose the identity of verified client certificates
+		middleware.GetHead,        // Answer HEAD requests with GET routes, if there is no explicit HEAD route
+	)
+
+	// Setup global rate limit
+	if r.modules.RateLimit != nil {
+		r.globalLimiter = tokenbucket.New(r.modules.RateLimit)
+		r.limiters = append(r.limiters, r.globalLimiter)
+	}
+
+	// Serve health endpoints
+	if path := r.modules.Health.LivenessPath; path != "" {
+		r.router.Get(path, livenessHandler)
+		logger.Debug(fmt.Sprintf("- %s %s (+liveness)", http.MethodGet, path))
+	}
+	if path := r.modules.Health.ReadinessPath; path != "" {
+		r.router.Get(path, r.readinessHandler)
+		logger.Debug(fmt.Sprintf("- %s %s (+readiness)", http.MethodGet, path))
+	}
+
+	// Serve metrics, unless they have their own listener
+	if r.modules.Metrics != nil && !r.modules.Metrics.Separate() {
+		r.router.Method(http.MethodGet, r.modules.Metrics.Path, r.metrics.Handler())
+		logger.Debug(fmt.Sprintf("- %s %s (+metrics)", http.MethodGet, r.modules.Metrics.Path))
+	}
+
+	// Serve the OpenAPI document of all routes
+	if r.modules.OpenAPI != nil {
+		handler, err := openAPIHandler(routes, groups, r.modules)
+		if err != nil {
+			return fmt.Errorf("failed to generate the OpenAPI document: %w", err)
+		}
+		r.router.Get(r.modules.OpenAPI.Path, handler)
+		logger.Debug(fmt.Sprintf("- %s %s (+openapi)", http.MethodGet, r.modules.OpenAPI.Path))
+	}
 
 	// Register all routes
+	r.addRoutes(r.router, "", routes, logger)
+
+	// Mount each group as a sub-router
+	for _, group := range groups {
+		logger.Debug(group.String())
+		r.router.Route(group.Prefix, func(groupRouter chi.Router) {
+			r.addRoutes(groupRouter, group.Prefix, group.Routes, logger)
+		})
+	}
+
+	// Answer OPTIONS requests for all patterns without an explicit OPTIONS route
+	r.addOptionsRoutes(logger)
+
+	logger.Debug("route registration done")
+
+	return nil
+}
+
+// Paths whose requests are not written to the access log
+func (r *chirouter) unloggedPaths() []string {
+	if r.modules.Health.AccessLog {
+		return nil
+	}
+	return slices.DeleteFunc([]string{r.modules.Health.LivenessPath, r.modules.Health.ReadinessPath}, func(path string) bool { return path == "" })
+}
+
+// Register routes with their executers on the router, whose routes all share the prefix
+func (r *chirouter) addRoutes(router chi.Router, prefix string, routes []config.Route, logger *slog.Logger) {
 	for _, route := range routes {
+		// Routes of other kinds than exec have no executer and are served by their kind handler
 		executer, err := r.executerCollection.For(&route)
 		if err != nil {
 			logger.Error("no executer available for route " + route.String())
 			continue
 		}
-		r.addRoute(route, executer, logger)
+		authenticators, err := r.authenticators.For(route.Auth)
+		if err != nil {
+			logger.Error("route "+route.String()+" is not registered", slog.String("error", err.Error()))
+			continue
+		}
+		var verifier webhook.Verifier
+		if route.Webhook != nil {
+			verifier, err = webhookverifier.New(route.Webhook)
+			if err != nil {
+				logger.Error("route "+route.String()+" is not registered", slog.String("error", err.Error()))
+				continue
+			}
+		}
+		r.addRoute(router, prefix, route, executer, authenticators, verifier, logger)
 	}
-
-	logger.Debug("route registration done")
-
-	return nil
 }
 
 // Actual route registration with the handler function definition
-func (r *chirouter) addRoute(route config.Route, executor execution.Executer, logger *slog.Logger) {
+func (r *chirouter) addRoute(router chi.Router, prefix string, route config.Route, executor execution.Executer, authenticators []auth.Authenticator, verifier webhook.Verifier, logger *slog.Logger) {
 	routePattern, _ := strings.CutSuffix(route.Route, "/")
+	if routePattern == "" {
+		routePattern = "/"
+	}
 	options := []string{}
 
 	// Define handler for this route
 	optimizedRoute := OptimizeRoute(route)
-	routeHandler := r.handlerFor(&optimizedRoute, executor, logger)
+	registered := &registeredRoute{name: route.Method + " " + prefix + routePattern, route: &optimizedRoute, verifier: verifier}
+	if _, ok := r.routes[registered.name]; ok {
+		// Registering it would replace the route, even if both are served on different listeners
+		logger.Error("route " + registered.name + " is defined more than once and is not registered again")
+		return
+	}
+	r.routes[registered.name] = registered
+	var routeHandler http.HandlerFunc
+	if executor != nil {
+		routeHandler = r.handlerFor(registered, executor, logger)
+	} else {
+		routeHandler = kindHandlerFor(&optimizedRoute, logger)
+		options = append(options, optimizedRoute.Kind())
+	}
 
 	// Wrap in caching middleware if configured
-	if optimizedRoute.Caching && optimizedRoute.Method == http.MethodGet {
-		routeHandler = r.cacher.Cache(routeHandler)
+	if optimizedRoute.Caching && optimizedRoute.Method == http.MethodGet && r.cacher != nil {
+		routeHandler = cacheObserver(r.cacher, registered.name, r.metrics, routeHandler)
 		options = append(options, "caching")
 	}
 
+	// Wrap in webhook verification, before the body is streamed to the command
+	if verifier != nil {
+		routeHandler = webhookHandler(&optimizedRoute.Route, verifier, routeHandler, logger)
+		options = append(options, "webhook")
+	}
+
+	// Wrap in rate limits. Limits keyed by principal or parameter (which may be a claim) need the authenticated request
+	type routeLimit struct {
+		limiter ratelimit.Limiter
+		limit   *config.RateLimit
+	}
+	var limits []routeLimit
+	if r.globalLimiter != nil {
+		limits = append(limits, routeLimit{r.globalLimiter, r.modules.RateLimit})
+	}
+	if optimizedRoute.RateLimit != nil {
+		limiter := tokenbucket.New(optimizedRoute.RateLimit)
+		r.limiters = append(r.limiters, limiter)
+		limits = append(limits, routeLimit{limiter, optimizedRoute.RateLimit})
+		options = append(options, "ratelimit")
+	}
+	wrapLimits := func(authenticated bool) {
+		for i, l := range limits {
+			if (l.limit.Key != config.RateLimitKeyIP) == authenticated {
+				// The route limit is more specific, so it reports its state if present
+				routeHandler = rateLimitHandler(l.limiter, l.limit, &optimizedRoute, routeHandler, i == len(limits)-1, logger)
+			}
+		}
+	}
+	wrapLimits(true)
+
+	// Wrap in authentication, so cached responses are only served to authenticated requests
+	if len(authenticators) > 0 {
+		routeHandler = authHandler(&optimizedRoute.Route, authenticators, routeHandler, logger)
+		options = append(options, "auth")
+	}
+	wrapLimits(false)
+
+	// Wrap in address lists, so denied clients do not use up rate limits
+	if len(optimizedRoute.AllowFrom) > 0 || len(optimizedRoute.DenyFrom) > 0 {
+		allowFrom, _ := config.ParsePrefixes(optimizedRoute.AllowFrom)
+		denyFrom, _ := config.ParsePrefixes(optimizedRoute.DenyFrom)
+		routeHandler = addressFilter(allowFrom, denyFrom, &optimizedRoute.Route, routeHandler)
+		options = append(options, "addresses")
+	}
+
+	// Wrap in maintenance, which is toggled by the admin API
+	routeHandler = maintenanceHandler(registered, routeHandler)
+
+	// Wrap in the body limit of the server, so the error format of the route is used
+	routeHandler = bodyLimitHandler(&optimizedRoute.Route, routeHandler)
+
+	// Wrap in CORS handling if enabled for the route or server-wide
+	cors := cmp.Or(optimizedRoute.CORS, r.modules.CORS)
+	if cors.Enabled() {
+		routeHandler = corsHandler(cors, routeHandler)
+		options = append(options, "cors")
+	} else {
+		cors = nil
+	}
+
+	// Wrap in the listener filter, so the route does not exist for other listeners
+	if len(optimizedRoute.Listeners) > 0 {
+		routeHandler = listenerFilter(optimizedRoute.Listeners, routeHandler)
+		options = append(options, "listeners")
+	}
+
 	// Register route
-	r.router.Method(optimizedRoute.Method, routePattern, routeHandler)
+	patterns := []string{routePattern}
+	if kind := optimizedRoute.Kind(); kind == "files" || kind == "proxy" {
+		// Trailing slashes are stripped, so the root of the wildcard needs its own pattern
+		if base, ok := strings.CutSuffix(routePattern, "/*"); ok {
+			patterns = append(patterns, cmp.Or(base, "/"))
+		}
+	}
+	for _, pattern := range patterns {
+		router.Method(optimizedRoute.Method, pattern, routeHandler)
+		fullPattern := prefix + pattern
+		if _, ok := r.patterns[fullPattern]; !ok {
+			r.patterns[fullPattern] = &patternRoutes{router: router, pattern: pattern, listeners: make(map[string][]string), cors: make(map[string]*config.CORSConfig)}
+		}
+		r.patterns[fullPattern].methods = append(r.patterns[fullPattern].methods, optimizedRoute.Method)
+		r.patterns[fullPattern].listeners[optimizedRoute.Method] = optimizedRoute.Listeners
+		r.patterns[fullPattern].cors[optimizedRoute.Method] = cors
+	}
 
 	var optionsText string
 	if len(options) > 0 {
 		optionsText = fmt.Sprintf("(+%s)", strings.Join(options, ","))
 	}
 
-	logger.Debug(fmt.Sprintf("- %s %s %s", route.Method, routePattern, optionsText))
+	logger.Debug(fmt.Sprintf("- %s %s %s", route.Method, prefix+routePattern, optionsText))
+}
+
+// Register OPTIONS handlers that list the allowed methods of a pattern, and answer unknown methods with them.
+// Methods of routes served on other listeners are left out, and patterns without any are answered with 404 Not Found
+func (r *chirouter) addOptionsRoutes(logger *slog.Logger) {
+	for fullPattern, routes := range r.patterns {
+		if slices.Contains(routes.methods, http.MethodOptions) {
+			continue
+		}
+		routes.router.Method(http.MethodOptions, routes.pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			allowHeader, ok := routes.allowHeader(server.ListenerFromContext(req.Context()))
+			if !ok {
+				http.NotFound(w, req)
+				return
+			}
+			w.Header().Set("Allow", allowHeader)
+			w.Header().Add("Server", ServerHeader)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		logger.Debug(fmt.Sprintf("- %s %s (Allow: %s)", http.MethodOptions, fullPattern, strings.Join(routes.methods, ", ")))
+	}
+
+	r.router.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request) {
+		// Every pattern has an OPTIONS route, which is used to find the pattern of this request
+		if routes, ok := r.patterns[r.router.Find(chi.NewRouteContext(), http.MethodOptions, requestPath(req))]; ok {
+			allowHeader, ok := routes.allowHeader(server.ListenerFromContext(req.Context()))
+			if !ok {
+				http.NotFound(w, req)
+				return
+			}
+			w.Header().Set("Allow", allowHeader)
+		}
+		w.Header().Add("Server", ServerHeader)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+}
+
+// Returns the methods of the routes served on the listener
+func (p *patternRoutes) methodsFor(listener string) []string {
+	return slices.DeleteFunc(slices.Clone(p.methods), func(method string) bool {
+		listeners := p.listeners[method]
+		return len(listeners) > 0 && !slices.Contains(listeners, listener)
+	})
+}
+
+// Returns the value of the Allow header for requests of the listener, or false if no route of the pattern is served on it
+func (p *patternRoutes) allowHeader(listener string) (string, bool) {
+	methods := p.methodsFor(listener)
+	if len(methods) == 0 {
+		return "", false
+	}
+
+	// GET routes also answer HEAD requests
+	allowed := append(methods, http.MethodOptions)
+	if slices.Contains(methods, http.MethodGet) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	slices.Sort(allowed)
+	return strings.Join(slices.Compact(allowed), ", "), true
 }
 
-func (r *chirouter) handlerFor(route *OptimizedRoute, executor execution.Executer, logger *slog.Logger) http.HandlerFunc {
+// Full request path used for routing, without trailing slash
+func requestPath(req *http.Request) string {
+	path := req.URL.Path
+	if req.URL.RawPath != "" {
+		path = req.URL.RawPath
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+func (r *chirouter) handlerFor(registered *registeredRoute, executor execution.Executer, logger *slog.Logger) http.HandlerFunc {
+	route := registered.route
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
+		logger := logging.FromContextOr(ctx, logger)
 
 		// Execution config
 		execConfig := execution.ConfigFromRoute(&route.Route)
+		execConfig.Request = req
 		if route.AllowBody {
 			execConfig.Stdin = req.Body
 		}
 
-		// Load parameters as env variables
+		// Load parameters as env variables, and those added by middlewares like the authenticated principal
+		_, paramSpan := tracing.Start(ctx, "collect parameters")
 		execConfig.Env = route.parameters.For(req)
+		maps.Copy(execConfig.Env, params.EnvFromContext(ctx))
+		paramSpan.End()
+
+		// Track the execution and its local processes, so it can be listed and killed
+		running := r.executions.Start(registered.name, params.EnvFromContext(ctx)[EnvRequestID])
+		execConfig.Started = func(process *os.Process) { r.executions.AddProcess(running, process) }
 
-		// On handle, start executor for route
-		result, exitCode, err := executor.Execute(ctx, execConfig)
+		// On handle, start executor for route. The command continues the trace as child of the execution span
+		execCtx, execSpan := tracing.Start(ctx, "execute")
+		addTraceEnv(execConfig.Env, execSpan)
+		startTime := time.Now()
+		r.metrics.ExecutionStarted()
+		result, exitCode, err := executor.Execute(execCtx, execConfig)
+		r.executions.Finish(running)
+		exitCodeLabel := strconv.Itoa(exitCode)
+		if err != nil {
+			exitCodeLabel = "error"
+			execSpan.SetError(err.Error())
+		} else {
+			execSpan.SetAttribute("process.exit.code", exitCode)
+		}
+		execSpan.End()
+		r.metrics.ExecutionFinished(chi.RouteContext(ctx).RoutePattern(), exitCodeLabel, time.Since(startTime))
 		if err != nil {
-			// Unexpected error, code 500, no response body
+			// Unexpected error, code 500 (or 504 on timeout), no response body unless problem details are enabled
 			logger.ErrorContext(ctx,
 				"unexpected error while handling route",
 				slog.String("error", err.Error()),
 				slog.String("route", route.Route.Route),
 			)
-			w.WriteHeader(http.StatusInternalServerError)
+			problem := Problem{Type: ProblemExecutionFailed, Status: http.StatusInternalServerError, Detail: "The command could not be executed"}
+			if errors.Is(err, context.DeadlineExceeded) {
+				problem = Problem{Type: ProblemExecutionTimeout, Status: http.StatusGatewayTimeout, Detail: "The command did not finish in time"}
+			}
+			w.Header().Add("Server", ServerHeader)
+			writeError(w, req, &route.Route, problem)
 
 			return
 		}
 
 		// Load response config for exit code
-		exitResponse := route.ExitCodeResponse(exitCode)
+		exitResponse := route.ExitCodeResponse(exitCode, result)
 
 		// Set headers (default and exit code related)
 		for header, value := range route.Headers {
 			w.Header().Add(header, value)
 		}
+		for header, values := range result.Header {
+			w.Header()[header] = values
+		}
 		for header, value := range exitResponse.Headers {
 			w.Header().Add(header, value)
 		}
 		// Add Server header
 		w.Header().Add("Server", ServerHeader)
 
-		// Respond with command result and mapped status code from exit code
-		w.WriteHeader(exitResponse.StatusCode)
-		if buffer := exitResponse.ResponseBufferFor(result); buffer != nil {
+		// Respond with command result and mapped status code from exit code, unless the executer reported one
+		statusCode := exitResponse.StatusCode
+		if result.StatusCode != 0 {
+			statusCode = result.StatusCode
+		}
+		buffer := exitResponse.ResponseBufferFor(result)
+
+		// Errors with problem details carry the response stream as detail
+		if statusCode >= http.StatusBadRequest && route.ErrorFormat == config.ErrorFormatProblem {
+			problem := Problem{Type: ProblemMappedExitCode, Status: statusCode, ExitCode: &exitCode}
+			if buffer != nil {
+				problem.Detail = strings.TrimSpace(buffer.String())
+			}
+			writeProblem(w, req, &route.Route, problem)
+			return
+		}
+
+		w.WriteHeader(statusCode)
+		if buffer != nil {
 			w.Write(buffer.Bytes())
 		}
 
@@ -0,0 +1,289 @@
+package chirouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/process"
+	"github.com/bdoerfchen/webcmd/src/services/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// An executer returning a fixed result
+type fakeExecuter struct {
+	exitCode int
+	stdout   string
+	stderr   string
+	echoEnv  string // Name of an env variable written to stdout
+	err      error
+	lastEnv  map[string]string // Env of the last execution
+}
+
+func (e *fakeExecuter) Execute(ctx context.Context, config execution.Config) (*process.Process, int, error) {
+	if e.err != nil {
+		return nil, 0, e.err
+	}
+
+	e.lastEnv = config.Env
+	proc := &process.Process{}
+	stdout, stderr := proc.Writers()
+	stdout.Write([]byte(e.stdout))
+	if e.echoEnv != "" {
+		stdout.Write([]byte(config.Env[e.echoEnv]))
+	}
+	stderr.Write([]byte(e.stderr))
+	return proc, e.exitCode, nil
+}
+
+func (e *fakeExecuter) Describe() (execution.ExecMode, []any) {
+	return execution.ModeProc, []any{}
+}
+
+// Create a handler serving the routes, all executed with the given executer
+func testHandler(t *testing.T, executer execution.Executer, routes ...config.Route) http.Handler {
+	return testGroupHandler(t, executer, routes, nil)
+}
+
+// Create a handler serving the routes and groups. Routes without kind are executed with the given executer
+func testGroupHandler(t *testing.T, executer execution.Executer, routes []config.Route, groups []config.RouteGroup) http.Handler {
+	var executers execution.ExecuterCollection
+	executers.Add(executer)
+
+	prepare := func(routes []config.Route) {
+		for i := range routes {
+			if routes[i].Kind() == "" {
+				routes[i].Exec.Proc = &config.ExecProc{Path: "test"}
+			}
+			routes[i].Check()
+		}
+	}
+	prepare(routes)
+	for i := range groups {
+		groups[i].Check()
+		prepare(groups[i].Routes)
+	}
+
+	router := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}})
+	require.NoError(t, router.Register(context.Background(), routes, groups))
+	return router.Handler()
+}
+
+func testRoute(pattern string, errorFormat config.ErrorFormat, mappings ...config.ExitCodeMapping) config.Route {
+	route := config.DefaultRoute()
+	route.Route = pattern
+	route.ErrorFormat = errorFormat
+	route.StatusCodes = append(mappings, route.StatusCodes...)
+	return route
+}
+
+func TestProblemResponses(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		Executer        *fakeExecuter
+		Mappings        []config.ExitCodeMapping
+		ExpectedProblem Problem
+	}{
+		{
+			Name:            "executer failure",
+			Executer:        &fakeExecuter{err: fmt.Errorf("broken")},
+			ExpectedProblem: Problem{Type: ProblemExecutionFailed, Title: "Internal Server Error", Status: 500, Detail: "The command could not be executed"},
+		},
+		{
+			Name:            "timeout",
+			Executer:        &fakeExecuter{err: fmt.Errorf("waiting: %w", context.DeadlineExceeded)},
+			ExpectedProblem: Problem{Type: ProblemExecutionTimeout, Title: "Gateway Timeout", Status: 504, Detail: "The command did not finish in time"},
+		},
+		{
+			Name:            "mapped exit code with stderr",
+			Executer:        &fakeExecuter{exitCode: 3, stdout: "out", stderr: "file not found\n"},
+			Mappings:        []config.ExitCodeMapping{{ExitCode: exitCode(3), StatusCode: 404, ResponseStream: config.StdErr}},
+			ExpectedProblem: Problem{Type: ProblemMappedExitCode, Title: "Not Found", Status: 404, Detail: "file not found", ExitCode: exitCode(3)},
+		},
+		{
+			Name:            "mapped exit code without detail",
+			Executer:        &fakeExecuter{exitCode: 1, stderr: "secret"},
+			Mappings:        []config.ExitCodeMapping{{StatusCode: 500, ResponseStream: config.None}},
+			ExpectedProblem: Problem{Type: ProblemMappedExitCode, Title: "Internal Server Error", Status: 500, ExitCode: exitCode(1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			handler := testHandler(t, tc.Executer, testRoute("/problem", config.ErrorFormatProblem, tc.Mappings...))
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/problem", nil))
+
+			assert.Equal(t, tc.ExpectedProblem.Status, recorder.Code)
+			assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+
+			var problem Problem
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+			tc.ExpectedProblem.Instance = "/problem"
+			tc.ExpectedProblem.Route = "GET /problem"
+			tc.ExpectedProblem.RequestID = recorder.Header().Get("X-Request-Id")
+			assert.Equal(t, tc.ExpectedProblem, problem)
+		})
+	}
+}
+
+func TestPlainErrorResponses(t *testing.T) {
+	handler := testHandler(t, &fakeExecuter{err: fmt.Errorf("broken")}, testRoute("/plain", ""))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/plain", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestProblemNotUsedForSuccess(t *testing.T) {
+	handler := testHandler(t, &fakeExecuter{stdout: "ok"}, testRoute("/ok", config.ErrorFormatProblem))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok", recorder.Body.String())
+}
+
+func TestMethods(t *testing.T) {
+	patch := testRoute("/items/{id}", "")
+	patch.Method = http.MethodPatch
+	explicitHead := testRoute("/head", "")
+	explicitHead.Method = http.MethodHead
+	server := httptest.NewServer(testHandler(t, &fakeExecuter{stdout: "body"},
+		testRoute("/items/{id}", ""),
+		patch,
+		explicitHead,
+	))
+	defer server.Close()
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		ExpectedStatusCode int
+		ExpectedBody       string
+		ExpectedAllow      string
+	}{
+		{Name: "get", Method: http.MethodGet, Path: "/items/1", ExpectedStatusCode: 200, ExpectedBody: "body"},
+		{Name: "patch", Method: http.MethodPatch, Path: "/items/1", ExpectedStatusCode: 200, ExpectedBody: "body"},
+		{Name: "head from get", Method: http.MethodHead, Path: "/items/1", ExpectedStatusCode: 200},
+		{Name: "explicit head", Method: http.MethodHead, Path: "/head", ExpectedStatusCode: 200},
+		{Name: "options", Method: http.MethodOptions, Path: "/items/1", ExpectedStatusCode: 204, ExpectedAllow: "GET, HEAD, OPTIONS, PATCH"},
+		{Name: "options for head", Method: http.MethodOptions, Path: "/head", ExpectedStatusCode: 204, ExpectedAllow: "HEAD, OPTIONS"},
+		{Name: "not allowed", Method: http.MethodDelete, Path: "/items/1", ExpectedStatusCode: 405, ExpectedAllow: "GET, HEAD, OPTIONS, PATCH"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.Method, server.URL+tc.Path, nil)
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ExpectedStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.ExpectedBody, string(body))
+			assert.Equal(t, tc.ExpectedAllow, resp.Header.Get("Allow"))
+		})
+	}
+}
+
+func TestGroups(t *testing.T) {
+	post := testRoute("/items", "")
+	post.Method = http.MethodPost
+	server := httptest.NewServer(testGroupHandler(t, &fakeExecuter{stdout: "body"},
+		[]config.Route{testRoute("/items", "")},
+		[]config.RouteGroup{{
+			Prefix: "/api",
+			Routes: []config.Route{testRoute("/", ""), testRoute("/items", ""), post},
+		}},
+	))
+	defer server.Close()
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		ExpectedStatusCode int
+		ExpectedAllow      string
+	}{
+		{Name: "root route", Method: http.MethodGet, Path: "/items", ExpectedStatusCode: 200},
+		{Name: "group root", Method: http.MethodGet, Path: "/api", ExpectedStatusCode: 200},
+		{Name: "group root with slash", Method: http.MethodGet, Path: "/api/", ExpectedStatusCode: 200},
+		{Name: "group route", Method: http.MethodPost, Path: "/api/items", ExpectedStatusCode: 200},
+		{Name: "group options", Method: http.MethodOptions, Path: "/api/items", ExpectedStatusCode: 204, ExpectedAllow: "GET, HEAD, OPTIONS, POST"},
+		{Name: "root options", Method: http.MethodOptions, Path: "/items", ExpectedStatusCode: 204, ExpectedAllow: "GET, HEAD, OPTIONS"},
+		{Name: "group not allowed", Method: http.MethodDelete, Path: "/api/items", ExpectedStatusCode: 405, ExpectedAllow: "GET, HEAD, OPTIONS, POST"},
+		{Name: "group not found", Method: http.MethodGet, Path: "/api/missing", ExpectedStatusCode: 404},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.Method, server.URL+tc.Path, nil)
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tc.ExpectedStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.ExpectedAllow, resp.Header.Get("Allow"))
+		})
+	}
+}
+
+func TestConflictingGroups(t *testing.T) {
+	var executers execution.ExecuterCollection
+	executers.Add(&fakeExecuter{})
+	route := testRoute("/items", "")
+	route.Exec.Proc = &config.ExecProc{Path: "test"}
+	route.Check()
+	groups := []config.RouteGroup{{Prefix: "/api", Routes: []config.Route{route}}, {Prefix: "/api", Routes: []config.Route{route}}}
+
+	router := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}})
+	assert.Error(t, router.Register(context.Background(), nil, groups))
+}
+
+func TestDuplicateRoutes(t *testing.T) {
+	first := testRoute("/same", "")
+	first.Static = &config.RouteStatic{Body: "first"}
+	first.Listeners = []string{"public"}
+	second := testRoute("/same/", "")
+	second.Static = &config.RouteStatic{Body: "second"}
+	second.Listeners = []string{"internal"}
+	handler := testHandler(t, &fakeExecuter{}, first, second)
+
+	testCases := []struct {
+		Name               string
+		Listener           string
+		ExpectedStatusCode int
+		ExpectedBody       string
+	}{
+		{Name: "first route", Listener: "public", ExpectedStatusCode: 200, ExpectedBody: "first"},
+		{Name: "duplicate not registered", Listener: "internal", ExpectedStatusCode: 404},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/same", nil)
+			handler.ServeHTTP(recorder, req.WithContext(server.WithListener(req.Context(), tc.Listener)))
+
+			assert.Equal(t, tc.ExpectedStatusCode, recorder.Code)
+			if tc.ExpectedBody != "" {
+				assert.Equal(t, tc.ExpectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
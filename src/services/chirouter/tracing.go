@@ -0,0 +1,45 @@
+package chirouter
+
+import (
+	"net/http"
+
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware starting a server span for each request, as child of the trace context sent by the client
+func (r *chirouter) tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		parent, _ := tracing.ParseTraceparent(req.Header.Get("traceparent"), req.Header.Get("tracestate"))
+		span := r.tracer.Start(req.Method, tracing.KindServer, parent)
+		defer span.End()
+		responseTracker := &trackingResponseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(responseTracker, req.WithContext(tracing.ContextWithSpan(req.Context(), span)))
+
+		// The route pattern is only known after routing
+		if routeContext := chi.RouteContext(req.Context()); routeContext != nil && routeContext.RoutePattern() != "" {
+			span.SetName(req.Method + " " + routeContext.RoutePattern())
+			span.SetAttribute("http.route", routeContext.RoutePattern())
+		}
+		span.SetAttribute("http.request.method", req.Method)
+		span.SetAttribute("url.path", req.URL.Path)
+		span.SetAttribute("client.address", req.RemoteAddr)
+		span.SetAttribute("http.response.status_code", responseTracker.StatusCode())
+		if responseTracker.StatusCode() >= 500 {
+			span.SetError(http.StatusText(responseTracker.StatusCode()))
+		}
+	})
+}
+
+// Add the trace context of the span to the env variables, so commands can continue the trace
+func addTraceEnv(env map[string]string, span tracing.Span) {
+	spanContext := span.Context()
+	if !spanContext.IsValid() {
+		return
+	}
+	env[tracing.EnvTraceparent] = spanContext.Traceparent()
+	if spanContext.State != "" {
+		env[tracing.EnvTracestate] = spanContext.State
+	}
+}
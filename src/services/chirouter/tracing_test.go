@@ -0,0 +1,117 @@
+package chirouter
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
+	"github.com/bdoerfchen/webcmd/src/services/otlptracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A stand-in OTLP collector recording the exported spans
+type fakeCollector struct {
+	mutex sync.Mutex
+	spans []map[string]any
+}
+
+func (c *fakeCollector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []map[string]any
+			}
+		}
+	}
+	if req.URL.Path != "/v1/traces" || req.Header.Get("Content-Type") != "application/json" || json.NewDecoder(req.Body).Decode(&body) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, resourceSpans := range body.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			c.spans = append(c.spans, scopeSpans.Spans...)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	collector := &fakeCollector{}
+	collectorServer := httptest.NewServer(collector)
+	defer collectorServer.Close()
+	tracingConfig := &config.TracingConfig{Endpoint: collectorServer.URL}
+	tracingConfig.Check()
+	tracer := otlptracer.New(tracingConfig, slog.Default())
+
+	route := testRoute("/items/{id}", "")
+	route.Exec.Proc = &config.ExecProc{Path: "test"}
+	route.Check()
+	var executers execution.ExecuterCollection
+	executer := &fakeExecuter{echoEnv: tracing.EnvTraceparent}
+	executers.Add(executer)
+	router := New(Options{Executers: &executers, Modules: &config.ModulesConfig{}, Tracer: tracer})
+	require.NoError(t, router.Register(context.Background(), []config.Route{route}, nil))
+
+	testCases := []struct {
+		Name          string
+		Traceparent   string
+		ExpectedTrace string // Trace ID of the command, empty for a new trace
+	}{
+		{Name: "remote parent", Traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ExpectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{Name: "invalid parent", Traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{Name: "no parent"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+			req.Header.Set("traceparent", tc.Traceparent)
+			req.Header.Set("tracestate", "vendor=value")
+			recorder := httptest.NewRecorder()
+			router.Handler().ServeHTTP(recorder, req)
+
+			commandParent, ok := tracing.ParseTraceparent(recorder.Body.String(), "")
+			require.True(t, ok, "command got traceparent '%s'", recorder.Body.String())
+			assert.True(t, commandParent.Sampled)
+			if tc.ExpectedTrace != "" {
+				assert.True(t, strings.HasPrefix(recorder.Body.String(), "00-"+tc.ExpectedTrace))
+				assert.Equal(t, "vendor=value", executer.lastEnv[tracing.EnvTracestate])
+			} else {
+				assert.NotContains(t, recorder.Body.String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+			}
+		})
+	}
+
+	// All spans are exported on shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, tracer.Shutdown(ctx))
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	require.Len(t, collector.spans, 3*len(testCases))
+	// The spans of the first request form a tree below the remote parent
+	server, parameters, execute := collector.spans[2], collector.spans[0], collector.spans[1]
+	assert.Equal(t, "GET /items/{id}", server["name"])
+	assert.Equal(t, "00f067aa0ba902b7", server["parentSpanId"])
+	assert.Equal(t, float64(tracing.KindServer), server["kind"])
+	assert.Equal(t, "collect parameters", parameters["name"])
+	assert.Equal(t, server["spanId"], parameters["parentSpanId"])
+	assert.Equal(t, "execute", execute["name"])
+	assert.Equal(t, server["spanId"], execute["parentSpanId"])
+	for _, span := range []map[string]any{server, parameters, execute} {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span["traceId"])
+	}
+}
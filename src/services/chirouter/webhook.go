@@ -0,0 +1,63 @@
+package chirouter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/webhook"
+)
+
+const (
+	ProblemInvalidSignature = "urn:webcmd:problem:invalid-signature"
+	ProblemReplayed         = "urn:webcmd:problem:replayed"
+	ProblemBodyTooLarge     = "urn:webcmd:problem:body-too-large"
+)
+
+// Largest webhook payload that is read for verification. Matches the limit of GitHub
+const maxWebhookBody = 25 << 20
+
+// Wrap a route handler to verify webhook deliveries first. The body is read completely, so it is verified before it is streamed to the command
+func webhookHandler(route *config.Route, verifier webhook.Verifier, handler http.HandlerFunc, logger *slog.Logger) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxWebhookBody+1))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.Header().Add("Server", ServerHeader)
+			writeError(w, req, route, Problem{Type: ProblemBodyTooLarge, Status: http.StatusRequestEntityTooLarge, Detail: "The webhook payload is too large"})
+			return
+		} else if err != nil {
+			w.Header().Add("Server", ServerHeader)
+			writeError(w, req, route, Problem{Type: ProblemInvalidSignature, Status: http.StatusBadRequest, Detail: "The request body could not be read"})
+			return
+		}
+		if len(body) > maxWebhookBody {
+			w.Header().Add("Server", ServerHeader)
+			writeError(w, req, route, Problem{Type: ProblemBodyTooLarge, Status: http.StatusRequestEntityTooLarge, Detail: "The webhook payload is too large"})
+			return
+		}
+
+		if err := verifier.Verify(req.Header, body); err != nil {
+			logger.WarnContext(req.Context(),
+				"webhook verification failed",
+				slog.String("error", err.Error()),
+				slog.String("route", route.Route),
+			)
+			w.Header().Add("Server", ServerHeader)
+			if errors.Is(err, webhook.ErrReplayed) {
+				writeError(w, req, route, Problem{Type: ProblemReplayed, Status: http.StatusConflict, Detail: "The delivery was already received"})
+			} else if errors.Is(err, webhook.ErrExpired) {
+				writeError(w, req, route, Problem{Type: ProblemInvalidSignature, Status: http.StatusUnauthorized, Detail: "The signed timestamp of the delivery is too old or in the future"})
+			} else {
+				writeError(w, req, route, Problem{Type: ProblemInvalidSignature, Status: http.StatusUnauthorized, Detail: "The delivery has no valid signature"})
+			}
+			return
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		handler(w, req)
+	})
+}
@@ -3,8 +3,10 @@ package configloader
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/bdoerfchen/webcmd/src/common/config"
@@ -70,11 +72,58 @@ func (l *configLoader) Load(ctx context.Context, path string) (*config.AppConfig
 	copier.CopyWithOption(&result.Modules, &parsedConfig.Modules, copier.Option{IgnoreEmpty: true, DeepCopy: true})
 	for _, configRoute := range parsedConfig.Routes {
 		// Go over parsed config's routes and append their content overlayed on the default to the result config
-		copiedRoute := config.DefaultRoute()
-		copier.CopyWithOption(&copiedRoute, &configRoute, copier.Option{IgnoreEmpty: true})
-		copiedRoute.StatusCodes = append(config.DefaultRoute().StatusCodes, configRoute.StatusCodes...)
-		result.Routes = append(result.Routes, copiedRoute)
+		result.Routes = append(result.Routes, overlayDefaultRoute(configRoute))
+	}
+	for _, group := range parsedConfig.Groups {
+		// Merge group defaults into each member route before overlaying them on the default
+		for i, configRoute := range group.Routes {
+			group.Routes[i] = overlayDefaultRoute(mergeGroupDefaults(&group, configRoute))
+		}
+		result.Groups = append(result.Groups, group)
 	}
 
 	return &result, nil
 }
+
+// Returns the path of the config file that is loaded for the given path
+func FilePath(path string) string {
+	if path == "" {
+		return DefaultConfigFile
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, DefaultConfigFile)
+	}
+	return path
+}
+
+// Overlay a configured route onto the default route
+func overlayDefaultRoute(configRoute config.Route) config.Route {
+	copiedRoute := config.DefaultRoute()
+	copier.CopyWithOption(&copiedRoute, &configRoute, copier.Option{IgnoreEmpty: true})
+	// Mappings are matched in order, so the configured ones go first
+	copiedRoute.StatusCodes = append(configRoute.StatusCodes, config.DefaultRoute().StatusCodes...)
+	return copiedRoute
+}
+
+// Merge the defaults of a group into a member route. Values of the route take precedence
+func mergeGroupDefaults(group *config.RouteGroup, route config.Route) config.Route {
+	// Headers of the route overwrite group headers
+	headers := maps.Clone(group.Headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	maps.Copy(headers, route.Headers)
+	route.Headers = headers
+
+	// Parameters are loaded in order, so later route parameters overwrite group parameters with the same env name
+	route.Parameters = append(slices.Clone(group.Parameters), route.Parameters...)
+
+	// Mappings are matched in order, so the route's mappings are evaluated first
+	route.StatusCodes = append(slices.Clone(route.StatusCodes), group.StatusCodes...)
+
+	if len(route.Listeners) == 0 {
+		route.Listeners = slices.Clone(group.Listeners)
+	}
+
+	return route
+}
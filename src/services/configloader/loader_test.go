@@ -0,0 +1,121 @@
+package configloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGroups(t *testing.T) {
+	content := `
+groups:
+- prefix: /api
+  headers: {"X-Group": "group", "X-Both": "group"}
+  parameters:
+  - name: "env"
+    default: "production"
+  - name: "region"
+    default: "eu"
+  statusCodes:
+  - exitCode: 4
+    statusCode: 404
+  - statusCode: 503
+  listeners: ["internal"]
+  routes:
+  - route: /items
+    listeners: ["public"]
+    headers: {"X-Both": "route"}
+    parameters:
+    - name: "region"
+      default: "us"
+    statusCodes:
+    - exitCode: 4
+      statusCode: 410
+    exec:
+      shell:
+        command: "echo items"
+  - route: /other
+    exec:
+      shell:
+        command: "echo other"
+`
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	result, err := New().Load(context.Background(), path)
+	require.NoError(t, err)
+	require.Len(t, result.Groups, 1)
+	require.Len(t, result.Groups[0].Routes, 2)
+	items, other := result.Groups[0].Routes[0], result.Groups[0].Routes[1]
+
+	// Route values take precedence
+	assert.Equal(t, map[string]string{"X-Group": "group", "X-Both": "route"}, items.Headers)
+	assert.Equal(t, []string{"env", "region", "region"}, paramNames(items.Parameters))
+	assert.Equal(t, "us", items.Parameters[2].Default)
+	assert.Equal(t, []int{410, 404, 503, 200}, statusCodes(items.StatusCodes))
+	assert.Equal(t, []string{"public"}, items.Listeners)
+
+	// Group defaults only
+	assert.Equal(t, map[string]string{"X-Group": "group", "X-Both": "group"}, other.Headers)
+	assert.Equal(t, []string{"env", "region"}, paramNames(other.Parameters))
+	assert.Equal(t, []int{404, 503, 200}, statusCodes(other.StatusCodes))
+	assert.Equal(t, []string{"internal"}, other.Listeners)
+
+	// Default route overlay
+	assert.Equal(t, "GET", other.Method)
+	assert.Equal(t, config.Both, other.ResponseStream)
+}
+
+func TestLoadExitCodeRanges(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Value         string
+		Expected      config.ExitCodeRange
+		ExpectedError bool
+	}{
+		{Name: "number", Value: `3`, Expected: config.ExitCodeRange{From: 3, To: 3}},
+		{Name: "range", Value: `"10-19"`, Expected: config.ExitCodeRange{From: 10, To: 19}},
+		{Name: "range with spaces", Value: `"10 - 19"`, Expected: config.ExitCodeRange{From: 10, To: 19}},
+		{Name: "negative number", Value: `"-1"`, Expected: config.ExitCodeRange{From: -1, To: -1}},
+		{Name: "negative range", Value: `"-5--1"`, Expected: config.ExitCodeRange{From: -5, To: -1}},
+		{Name: "range across zero", Value: `"-5-5"`, Expected: config.ExitCodeRange{From: -5, To: 5}},
+		{Name: "invalid", Value: `"a-b"`, ExpectedError: true},
+		{Name: "missing end", Value: `"5-"`, ExpectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			content := "routes:\n- route: /\n  statusCodes:\n  - exitCodes: " + tc.Value + "\n    statusCode: 400\n  exec:\n    shell:\n      command: \"true\"\n"
+			path := filepath.Join(t.TempDir(), DefaultConfigFile)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+			result, err := New().Load(context.Background(), path)
+			if tc.ExpectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, result.Routes[0].StatusCodes[0].ExitCodes)
+			assert.Equal(t, tc.Expected, *result.Routes[0].StatusCodes[0].ExitCodes)
+		})
+	}
+}
+
+func paramNames(parameters []config.RouteParameter) (result []string) {
+	for _, param := range parameters {
+		result = append(result, param.Name)
+	}
+	return
+}
+
+func statusCodes(mappings []config.ExitCodeMapping) (result []int) {
+	for _, mapping := range mappings {
+		result = append(result, mapping.StatusCode)
+	}
+	return
+}
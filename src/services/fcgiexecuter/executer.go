@@ -0,0 +1,259 @@
+package fcgiexecuter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/process"
+	"github.com/bdoerfchen/webcmd/src/common/version"
+)
+
+// Each connection only carries a single request, so the id is always the same
+const requestID uint16 = 1
+
+// An executer that forwards requests to FastCGI applications
+type fcgiExecuter struct {
+	dialer net.Dialer
+}
+
+func New() *fcgiExecuter {
+	return &fcgiExecuter{
+		dialer: net.Dialer{Timeout: 5 * time.Second},
+	}
+}
+
+func (e *fcgiExecuter) Execute(ctx context.Context, config execution.Config) (proc *process.Process, exitCode int, err error) {
+	if config.Exec.FastCGI == nil {
+		return nil, 0, fmt.Errorf("missing fastcgi config")
+	}
+
+	// Connect to application
+	network, address := splitAddress(config.Exec.FastCGI.Address)
+	conn, err := e.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to connect to fastcgi application: %w", err)
+	}
+	defer conn.Close()
+	// Abort blocking reads and writes when the request is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	// Read body into memory when its length is unknown, as CONTENT_LENGTH is required by most applications
+	body := config.Stdin
+	contentLength := int64(0)
+	if body != nil {
+		contentLength = -1
+		if config.Request != nil {
+			contentLength = config.Request.ContentLength
+		}
+		if contentLength < 0 {
+			content, err := io.ReadAll(body)
+			if err != nil {
+				return nil, 0, fmt.Errorf("unable to read request body: %w", err)
+			}
+			body = bytes.NewReader(content)
+			contentLength = int64(len(content))
+		}
+	}
+
+	// Send request
+	params := buildParams(config, contentLength)
+	if err := sendRequest(conn, params, body); err != nil {
+		return nil, 0, fmt.Errorf("error while sending fastcgi request: %w", err)
+	}
+
+	// Receive and parse response
+	proc, exitCode, err = receiveResponse(conn)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error while reading fastcgi response: %w", err)
+	}
+
+	return proc, exitCode, nil
+}
+
+func (e *fcgiExecuter) Describe() (mode execution.ExecMode, attributes []any) {
+	return execution.ModeFastCGI, []any{}
+}
+
+// Split a configured address into network and address. Paths and "unix:" prefixed addresses are unix sockets
+func splitAddress(address string) (network string, result string) {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		return "unix", path
+	}
+	if strings.HasPrefix(address, "/") || strings.HasPrefix(address, ".") {
+		return "unix", address
+	}
+
+	return "tcp", address
+}
+
+// Build CGI params from the request metadata and the route's environment variables
+func buildParams(config execution.Config, contentLength int64) map[string]string {
+	// Route parameters, same as the environment of other executers. They are copied first, so they can not replace CGI params
+	params := make(map[string]string)
+	for key, value := range config.Env {
+		params[key] = value
+	}
+	params["GATEWAY_INTERFACE"] = "CGI/1.1"
+	params["SERVER_SOFTWARE"] = "webcmd/" + version.Full()
+	params["SCRIPT_FILENAME"] = config.Command
+	params["CONTENT_LENGTH"] = strconv.FormatInt(contentLength, 10)
+
+	if req := config.Request; req != nil {
+		params["REQUEST_METHOD"] = req.Method
+		params["REQUEST_URI"] = req.URL.RequestURI()
+		params["SCRIPT_NAME"] = req.URL.Path
+		params["DOCUMENT_URI"] = req.URL.Path
+		params["QUERY_STRING"] = req.URL.RawQuery
+		params["SERVER_PROTOCOL"] = req.Proto
+		params["CONTENT_TYPE"] = req.Header.Get("Content-Type")
+
+		if host, port, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+			params["REMOTE_ADDR"] = host
+			params["REMOTE_PORT"] = port
+		} else {
+			params["REMOTE_ADDR"] = req.RemoteAddr
+		}
+		if host, port, err := net.SplitHostPort(req.Host); err == nil {
+			params["SERVER_NAME"] = host
+			params["SERVER_PORT"] = port
+		} else {
+			params["SERVER_NAME"] = req.Host
+		}
+		if req.TLS != nil {
+			params["HTTPS"] = "on"
+		}
+
+		// Forward headers as HTTP_* params
+		params["HTTP_HOST"] = req.Host
+		for name, values := range req.Header {
+			key := "HTTP_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+			switch key {
+			case "HTTP_PROXY":
+				// Mitigate httpoxy, see https://httpoxy.org
+				continue
+			case "HTTP_CONTENT_TYPE", "HTTP_CONTENT_LENGTH":
+				// Passed as CONTENT_TYPE and CONTENT_LENGTH
+				continue
+			}
+			params[key] = strings.Join(values, ", ")
+		}
+	}
+
+	return params
+}
+
+// Send begin request, params and stdin streams
+func sendRequest(conn net.Conn, params map[string]string, body io.Reader) error {
+	writer := bufio.NewWriter(conn)
+
+	if err := writeRecord(writer, typeBeginRequest, requestID, beginRequestBody()); err != nil {
+		return err
+	}
+	if err := writeStream(writer, typeParams, requestID, encodeParams(params)); err != nil {
+		return err
+	}
+	if err := writeRecord(writer, typeParams, requestID, nil); err != nil {
+		return err
+	}
+
+	// Stream body in chunks of maximum record size
+	if body != nil {
+		chunk := make([]byte, maxContentLength)
+		for {
+			n, err := body.Read(chunk)
+			if n > 0 {
+				if err := writeRecord(writer, typeStdin, requestID, chunk[:n]); err != nil {
+					return err
+				}
+			}
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("unable to read request body: %w", err)
+			}
+		}
+	}
+	if err := writeRecord(writer, typeStdin, requestID, nil); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// Read records until the end of the request and parse the CGI response from stdout
+func receiveResponse(conn net.Conn) (*process.Process, int, error) {
+	var stdout bytes.Buffer
+	result := &process.Process{}
+	reader := bufio.NewReader(conn)
+
+	for {
+		rec, err := readRecord(reader)
+		if err != nil {
+			return nil, 0, err
+		}
+		if rec.header.RequestID != requestID {
+			continue
+		}
+
+		switch rec.header.Type {
+		case typeStdout:
+			stdout.Write(rec.content)
+		case typeStderr:
+			result.StdErr.Write(rec.content)
+		case typeEndRequest:
+			if len(rec.content) < 5 {
+				return nil, 0, fmt.Errorf("invalid end request record")
+			}
+			if protocolStatus := rec.content[4]; protocolStatus != statusRequestComplete {
+				return nil, 0, fmt.Errorf("request rejected by application with protocol status %v", protocolStatus)
+			}
+
+			appStatus := int(int32(binary.BigEndian.Uint32(rec.content[:4])))
+			if err := parseCGIResponse(&stdout, result); err != nil {
+				return nil, 0, err
+			}
+
+			return result, appStatus, nil
+		}
+	}
+}
+
+// Parse headers and body of a CGI response into the process result
+func parseCGIResponse(stdout *bytes.Buffer, result *process.Process) error {
+	reader := bufio.NewReader(stdout)
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("invalid response header: %w", err)
+	}
+	result.Header = http.Header(header)
+
+	// Status header defines the status code, or a redirect when only Location is given
+	if status := result.Header.Get("Status"); status != "" {
+		code, _, _ := strings.Cut(status, " ")
+		result.StatusCode, err = strconv.Atoi(code)
+		if err != nil {
+			return fmt.Errorf("invalid status header: %s", status)
+		}
+		result.Header.Del("Status")
+	} else if result.Header.Get("Location") != "" {
+		result.StatusCode = http.StatusFound
+	}
+
+	// Remaining content is the body
+	io.Copy(&result.StdOut, reader)
+	result.StdOutErr.Write(result.StdOut.Bytes())
+
+	return nil
+}
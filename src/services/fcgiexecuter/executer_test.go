@@ -0,0 +1,161 @@
+package fcgiexecuter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Start an in-process FastCGI application and return its address
+func startApplication(t *testing.T, network string, handler http.HandlerFunc) string {
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		address = filepath.Join(t.TempDir(), "fcgi.sock")
+	}
+
+	listener, err := net.Listen(network, address)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go fcgi.Serve(listener, handler)
+
+	if network == "unix" {
+		return "unix:" + address
+	}
+	return listener.Addr().String()
+}
+
+func execConfig(address string, req *http.Request, body io.Reader) execution.Config {
+	route := config.DefaultRoute()
+	route.Exec.FastCGI = &config.ExecFastCGI{Address: address, Script: "/srv/app/index.php"}
+
+	result := execution.ConfigFromRoute(&route)
+	result.Request = req
+	result.Stdin = body
+	result.Env = map[string]string{"WC_NAME": "webcmd"}
+	return result
+}
+
+func TestExecuteParams(t *testing.T) {
+	address := startApplication(t, "tcp", func(w http.ResponseWriter, r *http.Request) {
+		env := fcgi.ProcessEnv(r)
+		fmt.Fprintf(w, "%s %s %s %s %s", r.Method, r.URL.RequestURI(), r.Header.Get("X-Test"), env["SCRIPT_FILENAME"], env["WC_NAME"])
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/hello?planet=mars", nil)
+	req.Header.Set("X-Test", "header")
+	proc, exitCode, err := New().Execute(context.Background(), execConfig(address, req, nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, http.StatusOK, proc.StatusCode)
+	assert.Equal(t, "GET /hello?planet=mars header /srv/app/index.php webcmd", proc.StdOut.String())
+}
+
+func TestBuildParams(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader("body"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Content-Length", "4")
+	req.Header.Set("X-Test", "header")
+	config := execConfig("127.0.0.1:9000", req, nil)
+	config.Env["SCRIPT_FILENAME"] = "/etc/passwd"
+	config.Env["REQUEST_METHOD"] = "DELETE"
+
+	params := buildParams(config, 4)
+
+	assert.Equal(t, "/srv/app/index.php", params["SCRIPT_FILENAME"])
+	assert.Equal(t, http.MethodPost, params["REQUEST_METHOD"])
+	assert.Equal(t, "webcmd", params["WC_NAME"])
+	assert.Equal(t, "text/plain", params["CONTENT_TYPE"])
+	assert.Equal(t, "4", params["CONTENT_LENGTH"])
+	assert.Equal(t, "header", params["HTTP_X_TEST"])
+	assert.NotContains(t, params, "HTTP_CONTENT_TYPE")
+	assert.NotContains(t, params, "HTTP_CONTENT_LENGTH")
+}
+
+func TestExecuteBody(t *testing.T) {
+	address := startApplication(t, "unix", func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		w.Write([]byte(strings.ToUpper(string(content))))
+	})
+
+	testCases := []struct {
+		Name          string
+		ContentLength int64
+	}{
+		{Name: "known length", ContentLength: 16 * 1024 * 10},
+		{Name: "unknown length", ContentLength: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			// Body exceeding a single record
+			content := strings.Repeat("abcdefghijklmnop", 1024*10)
+			req := httptest.NewRequest(http.MethodPost, "/upload", nil)
+			req.ContentLength = tc.ContentLength
+
+			proc, _, err := New().Execute(context.Background(), execConfig(address, req, strings.NewReader(content)))
+			require.NoError(t, err)
+
+			assert.Equal(t, strings.ToUpper(content), proc.StdOut.String())
+		})
+	}
+}
+
+func TestExecuteResponseHeaders(t *testing.T) {
+	address := startApplication(t, "tcp", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-App", "php")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	proc, exitCode, err := New().Execute(context.Background(), execConfig(address, req, nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, http.StatusNotFound, proc.StatusCode)
+	assert.Equal(t, "php", proc.Header.Get("X-App"))
+	assert.Equal(t, "application/json", proc.Header.Get("Content-Type"))
+	assert.Empty(t, proc.Header.Get("Status"))
+	assert.Equal(t, `{"error":"not found"}`, proc.StdOutErr.String())
+}
+
+func TestExecuteUnreachable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, _, err := New().Execute(context.Background(), execConfig("unix:"+filepath.Join(t.TempDir(), "missing.sock"), req, nil))
+	assert.Error(t, err)
+}
+
+func TestSplitAddress(t *testing.T) {
+	testCases := []struct {
+		Address         string
+		ExpectedNetwork string
+		ExpectedAddress string
+	}{
+		{Address: "127.0.0.1:9000", ExpectedNetwork: "tcp", ExpectedAddress: "127.0.0.1:9000"},
+		{Address: "unix:/run/php.sock", ExpectedNetwork: "unix", ExpectedAddress: "/run/php.sock"},
+		{Address: "/run/php.sock", ExpectedNetwork: "unix", ExpectedAddress: "/run/php.sock"},
+		{Address: "./php.sock", ExpectedNetwork: "unix", ExpectedAddress: "./php.sock"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Address, func(t *testing.T) {
+			network, address := splitAddress(tc.Address)
+			assert.Equal(t, tc.ExpectedNetwork, network)
+			assert.Equal(t, tc.ExpectedAddress, address)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package fcgiexecuter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// FastCGI record types and constants, see https://fastcgi-archives.github.io/FastCGI_Specification.html
+const (
+	fcgiVersion = 1
+
+	typeBeginRequest = 1
+	typeAbortRequest = 2
+	typeEndRequest   = 3
+	typeParams       = 4
+	typeStdin        = 5
+	typeStdout       = 6
+	typeStderr       = 7
+
+	roleResponder = 1
+
+	statusRequestComplete = 0
+
+	maxContentLength = 65535
+	headerLength     = 8
+)
+
+type recordHeader struct {
+	Version       uint8
+	Type          uint8
+	RequestID     uint16
+	ContentLength uint16
+	PaddingLength uint8
+	Reserved      uint8
+}
+
+type record struct {
+	header  recordHeader
+	content []byte
+}
+
+// Write a single record with the given content. Content must not exceed maxContentLength
+func writeRecord(w io.Writer, recordType uint8, requestID uint16, content []byte) error {
+	// Pad content to a multiple of 8 bytes as recommended by the spec
+	padding := uint8(-len(content) & 7)
+	header := recordHeader{
+		Version:       fcgiVersion,
+		Type:          recordType,
+		RequestID:     requestID,
+		ContentLength: uint16(len(content)),
+		PaddingLength: padding,
+	}
+
+	buffer := make([]byte, 0, headerLength+len(content)+int(padding))
+	buffer, _ = binary.Append(buffer, binary.BigEndian, header)
+	buffer = append(buffer, content...)
+	buffer = append(buffer, make([]byte, padding)...)
+
+	_, err := w.Write(buffer)
+	return err
+}
+
+// Write content as a stream of records, split by the maximum record size. Does not terminate the stream
+func writeStream(w io.Writer, recordType uint8, requestID uint16, content []byte) error {
+	for len(content) > 0 {
+		chunk := content[:min(len(content), maxContentLength)]
+		if err := writeRecord(w, recordType, requestID, chunk); err != nil {
+			return err
+		}
+		content = content[len(chunk):]
+	}
+
+	return nil
+}
+
+// Read the next record from the connection
+func readRecord(r io.Reader) (*record, error) {
+	var rec record
+	if err := binary.Read(r, binary.BigEndian, &rec.header); err != nil {
+		return nil, err
+	}
+	if rec.header.Version != fcgiVersion {
+		return nil, fmt.Errorf("unsupported fastcgi version %v", rec.header.Version)
+	}
+
+	buffer := make([]byte, int(rec.header.ContentLength)+int(rec.header.PaddingLength))
+	if _, err := io.ReadFull(r, buffer); err != nil {
+		return nil, err
+	}
+	rec.content = buffer[:rec.header.ContentLength]
+
+	return &rec, nil
+}
+
+// Body of the begin request record for the responder role. The connection is not kept open
+func beginRequestBody() []byte {
+	return []byte{0, roleResponder, 0, 0, 0, 0, 0, 0}
+}
+
+// Encode name-value pairs as defined by the spec: lengths below 128 use one byte, others four bytes
+func encodeParams(params map[string]string) []byte {
+	var result []byte
+	for name, value := range params {
+		result = appendLength(result, len(name))
+		result = appendLength(result, len(value))
+		result = append(result, name...)
+		result = append(result, value...)
+	}
+
+	return result
+}
+
+func appendLength(buffer []byte, length int) []byte {
+	if length < 128 {
+		return append(buffer, byte(length))
+	}
+
+	return binary.BigEndian.AppendUint32(buffer, uint32(length)|1<<31)
+}
@@ -0,0 +1,121 @@
+package healthmonitor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+)
+
+// Longest command output shown in readiness responses
+const maxOutput = 200
+
+// Evaluates readiness checks, and runs health check commands periodically
+type healthMonitor struct {
+	logger *slog.Logger
+
+	mutex  sync.Mutex
+	checks []namedCheck
+}
+
+type namedCheck struct {
+	name  string
+	check func() error
+}
+
+func New(logger *slog.Logger) *healthMonitor {
+	return &healthMonitor{logger: logger}
+}
+
+func (m *healthMonitor) AddCheck(name string, check func() error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.checks = append(m.checks, namedCheck{name: name, check: check})
+}
+
+func (m *healthMonitor) RemoveCheck(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.checks = slices.DeleteFunc(slices.Clone(m.checks), func(c namedCheck) bool { return c.name == name })
+}
+
+func (m *healthMonitor) Failures() map[string]string {
+	m.mutex.Lock()
+	checks := m.checks
+	m.mutex.Unlock()
+
+	failures := make(map[string]string)
+	for _, check := range checks {
+		if err := check.check(); err != nil {
+			failures[check.name] = err.Error()
+		}
+	}
+	return failures
+}
+
+// Run the command periodically until the context is done. Its last result is a readiness check, which fails until the first run finished
+func (m *healthMonitor) AddCommand(ctx context.Context, name string, healthCheck *config.HealthCheck) {
+	var mutex sync.Mutex
+	result := fmt.Errorf("not checked yet")
+	m.AddCheck(name, func() error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return result
+	})
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(healthCheck.Interval))
+		defer ticker.Stop()
+		for first := true; ; first = false {
+			err := runCommand(ctx, healthCheck)
+			mutex.Lock()
+			changed := first || (err == nil) != (result == nil)
+			result = err
+			mutex.Unlock()
+			if changed && err != nil {
+				m.logger.Warn("health check failed", slog.String("check", name), slog.String("error", err.Error()))
+			} else if changed {
+				m.logger.Info("health check succeeded", slog.String("check", name))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Run the command and return an error with its output if it fails
+func runCommand(ctx context.Context, healthCheck *config.HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(healthCheck.Timeout))
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, healthCheck.Path, healthCheck.Args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", time.Duration(healthCheck.Timeout))
+	}
+	if err != nil {
+		message := strings.TrimSpace(output.String())
+		if len(message) > maxOutput {
+			message = message[:maxOutput] + "..."
+		}
+		if message == "" {
+			return err
+		}
+		return fmt.Errorf("%w: %s", err, message)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package healthmonitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	monitor := New(slog.Default())
+	monitor.AddCheck("ready", func() error { return nil })
+	monitor.AddCheck("not ready", func() error { return fmt.Errorf("starting") })
+	for name, check := range map[string]*config.HealthCheck{
+		"succeeding": {Path: "true"},
+		"failing":    {Path: "sh", Args: []string{"-c", "echo broken; exit 3"}},
+		"slow":       {Path: "sleep", Args: []string{"5"}, Timeout: timem.Duration(50 * time.Millisecond)},
+	} {
+		check.Check()
+		monitor.AddCommand(ctx, name, check)
+	}
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, map[string]string{
+			"not ready": "starting",
+			"failing":   "exit status 3: broken",
+			"slow":      "timed out after 50ms",
+		}, monitor.Failures())
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Removed checks do not decide about readiness anymore
+	monitor.RemoveCheck("failing")
+	monitor.RemoveCheck("slow")
+	assert.Equal(t, map[string]string{"not ready": "starting"}, monitor.Failures())
+}
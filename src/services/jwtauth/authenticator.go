@@ -0,0 +1,253 @@
+package jwtauth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+)
+
+// The JWKS file is checked for changes at most once in this interval
+const jwksCheckInterval = 10 * time.Second
+
+// An authenticator accepting signed JWT bearer tokens
+type jwtAuthenticator struct {
+	config    *config.AuthJWT
+	keys      []*key // Configured keys
+	challenge string
+	now       func() time.Time
+
+	jwksMutex     sync.Mutex
+	jwksKeys      []*key    // Keys of the JWKS file
+	jwksModified  time.Time // Modification time of the loaded JWKS file
+	jwksCheckedAt time.Time
+}
+
+func New(config *config.AuthJWT) (*jwtAuthenticator, error) {
+	authenticator := &jwtAuthenticator{
+		config:    config,
+		challenge: fmt.Sprintf(`Bearer realm="%s"`, config.Realm),
+		now:       time.Now,
+	}
+
+	for i := range config.Keys {
+		key, err := loadKey(&config.Keys[i])
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %v: %w", i+1, err)
+		}
+		authenticator.keys = append(authenticator.keys, key)
+	}
+
+	if config.JWKSFile != "" {
+		if err := authenticator.reloadJWKS(); err != nil {
+			return nil, err
+		}
+	}
+
+	return authenticator, nil
+}
+
+// Load the keys of the JWKS file if it was modified since the last load
+func (a *jwtAuthenticator) reloadJWKS() error {
+	info, err := os.Stat(a.config.JWKSFile)
+	if err != nil {
+		return fmt.Errorf("unable to read jwks file: %w", err)
+	}
+	if info.ModTime().Equal(a.jwksModified) {
+		return nil
+	}
+
+	content, err := os.ReadFile(a.config.JWKSFile)
+	if err != nil {
+		return fmt.Errorf("unable to read jwks file: %w", err)
+	}
+	keys, err := parseJWKS(content)
+	if err != nil {
+		return fmt.Errorf("invalid jwks file %s: %w", a.config.JWKSFile, err)
+	}
+
+	a.jwksKeys = keys
+	a.jwksModified = info.ModTime()
+	return nil
+}
+
+// Returns the configured and current JWKS keys. A failed reload keeps the previous JWKS keys
+func (a *jwtAuthenticator) currentKeys() []*key {
+	if a.config.JWKSFile == "" {
+		return a.keys
+	}
+
+	a.jwksMutex.Lock()
+	defer a.jwksMutex.Unlock()
+	if now := a.now(); now.Sub(a.jwksCheckedAt) >= jwksCheckInterval {
+		a.jwksCheckedAt = now
+		a.reloadJWKS()
+	}
+
+	return append(slices.Clone(a.keys), a.jwksKeys...)
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func (a *jwtAuthenticator) Authenticate(req *http.Request) (*auth.Principal, error) {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, auth.ErrNoCredentials
+	}
+
+	claims, err := a.verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", auth.ErrInvalidCredentials, err)
+	}
+
+	principal := &auth.Principal{Claims: claims}
+	principal.Name, _ = principal.Claim(a.config.UserClaim)
+	return principal, nil
+}
+
+// Verify the signature and claims of a token and return its claims
+func (a *jwtAuthenticator) verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a signed jwt")
+	}
+
+	// Header
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if !slices.Contains(config.JWTAlgorithms, header.Alg) {
+		return nil, fmt.Errorf("unsupported algorithm '%s'", header.Alg)
+	}
+
+	// Signature
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	if !slices.ContainsFunc(a.currentKeys(), func(key *key) bool {
+		return key.algorithm == header.Alg && (key.id == "" || header.Kid == "" || key.id == header.Kid) && key.verify(signed, signature)
+	}) {
+		return nil, errors.New("signature does not match any key")
+	}
+
+	// Claims
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	if err := a.checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// Check the registered claims of a token
+func (a *jwtAuthenticator) checkClaims(claims map[string]any) error {
+	now := a.now()
+	leeway := time.Duration(a.config.Leeway)
+
+	if exp, ok, err := timeClaim(claims, "exp"); err != nil {
+		return err
+	} else if !ok && !a.config.AllowMissingExp {
+		return errors.New("token has no expiration time")
+	} else if ok && !now.Before(exp.Add(leeway)) {
+		return errors.New("token is expired")
+	}
+	if nbf, ok, err := timeClaim(claims, "nbf"); err != nil {
+		return err
+	} else if ok && now.Add(leeway).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+
+	principal := auth.Principal{Claims: claims}
+	if a.config.Issuer != "" && !principal.HasClaim("iss", a.config.Issuer) {
+		return errors.New("token has a different issuer")
+	}
+	if a.config.Audience != "" && !principal.HasClaim("aud", a.config.Audience) {
+		return errors.New("token is issued for a different audience")
+	}
+
+	return nil
+}
+
+// Read a numeric date claim
+func timeClaim(claims map[string]any, name string) (time.Time, bool, error) {
+	value, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("claim '%s' is not a number", name)
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("claim '%s' is not a number", name)
+	}
+
+	return time.Unix(0, int64(seconds*float64(time.Second))), true, nil
+}
+
+// Decode a base64url encoded JSON segment of a token
+func decodeSegment(segment string, target any) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// Verify the signature of the signed content
+func (k *key) verify(signed []byte, signature []byte) bool {
+	switch k.algorithm {
+	case "HS256":
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(signed)
+		return hmac.Equal(signature, mac.Sum(nil))
+	case "RS256":
+		hash := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(k.public.(*rsa.PublicKey), crypto.SHA256, hash[:], signature) == nil
+	case "ES256":
+		// Signature is the concatenation of r and s
+		if len(signature) != 64 {
+			return false
+		}
+		hash := sha256.Sum256(signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(k.public.(*ecdsa.PublicKey), hash[:], r, s)
+	}
+
+	return false
+}
+
+func (a *jwtAuthenticator) Challenge() string {
+	return a.challenge
+}
@@ -0,0 +1,200 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// Create a signed token with the signing function
+func signToken(t *testing.T, alg string, kid string, claims map[string]any, sign func(signed []byte) []byte) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(secret string) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		hash := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+		require.NoError(t, err)
+		return signature
+	}
+}
+
+func es256(t *testing.T, key *ecdsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		hash := sha256.Sum256(signed)
+		r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+		require.NoError(t, err)
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	directory := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	// RSA key as PEM file, EC key in JWKS file
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	rsaPath := filepath.Join(directory, "rsa.pem")
+	require.NoError(t, os.WriteFile(rsaPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	jwksPath := filepath.Join(directory, "jwks.json")
+	jwks := `{"keys": [{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": "` +
+		base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))) + `", "y": "` +
+		base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))) + `"}, {"kty": "RSA", "alg": "RS512", "n": "AQAB", "e": "AQAB"}]}`
+	require.NoError(t, os.WriteFile(jwksPath, []byte(jwks), 0o600))
+	t.Setenv("TEST_JWT_SECRET", "secret")
+
+	authenticator, err := New(&config.AuthJWT{
+		Keys: []config.AuthJWTKey{
+			{ID: "hmac", SecretEnv: "TEST_JWT_SECRET"},
+			{PublicKeyFile: rsaPath},
+		},
+		JWKSFile:  jwksPath,
+		Issuer:    "https://issuer.example.com",
+		Audience:  "webcmd",
+		Leeway:    timem.Duration(time.Minute),
+		UserClaim: "sub",
+		Realm:     "test",
+	})
+	require.NoError(t, err)
+	authenticator.now = func() time.Time { return now }
+	assert.Equal(t, `Bearer realm="test"`, authenticator.Challenge())
+
+	valid := func() map[string]any {
+		return map[string]any{"sub": "alice", "iss": "https://issuer.example.com", "aud": []string{"other", "webcmd"}, "exp": now.Add(time.Hour).Unix(), "scope": "read"}
+	}
+	with := func(name string, value any) map[string]any {
+		claims := valid()
+		claims[name] = value
+		return claims
+	}
+	without := func(name string) map[string]any {
+		claims := valid()
+		delete(claims, name)
+		return claims
+	}
+
+	testCases := []struct {
+		Name          string
+		Token         string
+		ExpectedError error
+	}{
+		{Name: "hs256", Token: signToken(t, "HS256", "hmac", valid(), hs256("secret"))},
+		{Name: "hs256 without kid", Token: signToken(t, "HS256", "", valid(), hs256("secret"))},
+		{Name: "rs256", Token: signToken(t, "RS256", "any", valid(), rs256(t, rsaKey))},
+		{Name: "es256 from jwks", Token: signToken(t, "ES256", "ec-1", valid(), es256(t, ecKey))},
+		{Name: "wrong secret", Token: signToken(t, "HS256", "hmac", valid(), hs256("guess")), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "wrong kid", Token: signToken(t, "HS256", "other", valid(), hs256("secret")), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "unknown key", Token: signToken(t, "ES256", "ec-1", valid(), es256(t, otherKey)), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "algorithm confusion", Token: signToken(t, "HS256", "", valid(), hs256(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "none algorithm", Token: signToken(t, "none", "", valid(), func([]byte) []byte { return nil }), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "expired", Token: signToken(t, "HS256", "", with("exp", now.Add(-2*time.Minute).Unix()), hs256("secret")), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "expired within leeway", Token: signToken(t, "HS256", "", with("exp", now.Add(-30*time.Second).Unix()), hs256("secret"))},
+		{Name: "without expiration", Token: signToken(t, "HS256", "", without("exp"), hs256("secret")), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "not yet valid", Token: signToken(t, "HS256", "", with("nbf", now.Add(2*time.Minute).Unix()), hs256("secret")), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "wrong issuer", Token: signToken(t, "HS256", "", with("iss", "https://evil.example.com"), hs256("secret")), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "wrong audience", Token: signToken(t, "HS256", "", with("aud", "other"), hs256("secret")), ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "malformed", Token: "abc.def", ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "no token", ExpectedError: auth.ErrNoCredentials},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.Token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.Token)
+			}
+
+			principal, err := authenticator.Authenticate(req)
+			if tc.ExpectedError != nil {
+				assert.ErrorIs(t, err, tc.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "alice", principal.Name)
+			assert.True(t, principal.HasScope("read"))
+		})
+	}
+
+	// Tokens without expiration are accepted if allowed
+	authenticator.config.AllowMissingExp = true
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "HS256", "", without("exp"), hs256("secret")))
+	_, err = authenticator.Authenticate(req)
+	assert.NoError(t, err)
+}
+
+func TestReloadJWKS(t *testing.T) {
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS := func(secret string, modified time.Time) {
+		jwks := `{"keys": [{"kty": "oct", "k": "` + base64.RawURLEncoding.EncodeToString([]byte(secret)) + `"}]}`
+		require.NoError(t, os.WriteFile(jwksPath, []byte(jwks), 0o600))
+		require.NoError(t, os.Chtimes(jwksPath, modified, modified))
+	}
+	writeJWKS("first", now.Add(-time.Hour))
+
+	authenticator, err := New(&config.AuthJWT{JWKSFile: jwksPath, UserClaim: "sub"})
+	require.NoError(t, err)
+	clock := now
+	authenticator.now = func() time.Time { return clock }
+
+	authenticate := func(secret string) error {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+signToken(t, "HS256", "", map[string]any{"sub": "alice", "exp": now.Add(time.Hour).Unix()}, hs256(secret)))
+		_, err := authenticator.Authenticate(req)
+		return err
+	}
+	require.NoError(t, authenticate("first"))
+
+	// The changed file is loaded with the next check
+	writeJWKS("second", now)
+	clock = clock.Add(jwksCheckInterval)
+	assert.NoError(t, authenticate("second"))
+	assert.ErrorIs(t, authenticate("first"), auth.ErrInvalidCredentials)
+
+	// A broken file keeps the previous keys
+	require.NoError(t, os.WriteFile(jwksPath, []byte("{"), 0o600))
+	clock = clock.Add(jwksCheckInterval)
+	assert.NoError(t, authenticate("second"))
+}
@@ -0,0 +1,189 @@
+package jwtauth
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+)
+
+// A key to verify token signatures with
+type key struct {
+	id        string // Matched against the kid header, if set
+	algorithm string // HS256, RS256 or ES256
+	secret    []byte
+	public    any // *rsa.PublicKey or *ecdsa.PublicKey
+}
+
+// Load a configured key
+func loadKey(keyConfig *config.AuthJWTKey) (*key, error) {
+	if keyConfig.Secret != "" || keyConfig.SecretEnv != "" {
+		secret := keyConfig.Secret
+		if keyConfig.SecretEnv != "" {
+			secret = os.Getenv(keyConfig.SecretEnv)
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("secret must not be empty")
+		}
+		return &key{id: keyConfig.ID, algorithm: "HS256", secret: []byte(secret)}, nil
+	}
+
+	content, err := os.ReadFile(keyConfig.PublicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read public key file: %w", err)
+	}
+	public, err := parsePublicKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key file %s: %w", keyConfig.PublicKeyFile, err)
+	}
+
+	return newPublicKey(keyConfig.ID, keyConfig.Algorithm, public)
+}
+
+// Parse a PEM encoded public key or certificate
+func parsePublicKey(content []byte) (any, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return certificate.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+// Create a key for a public key, checking that it fits the algorithm. The algorithm is derived from the key if empty
+func newPublicKey(id string, algorithm string, public any) (*key, error) {
+	var derived string
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		derived = "RS256"
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("only P-256 curve keys are supported")
+		}
+		derived = "ES256"
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+
+	if algorithm != "" && algorithm != derived {
+		return nil, fmt.Errorf("algorithm %s does not match %s key", algorithm, derived)
+	}
+
+	return &key{id: id, algorithm: derived, public: public}, nil
+}
+
+// A JSON web key as found in JWKS files
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// Symmetric
+	K string `json:"k"`
+}
+
+// Parse the signature keys of a JWKS file. Keys of other types or uses are skipped
+func parseJWKS(content []byte) ([]*key, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, err
+	}
+
+	var keys []*key
+	for i, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.key()
+		if err != nil {
+			return nil, fmt.Errorf("key %v: %w", i+1, err)
+		}
+		if key != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Convert the JSON web key. Returns nil for unsupported key types
+func (jwk *jsonWebKey) key() (*key, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	if jwk.Alg != "" && !slices.Contains(config.JWTAlgorithms, jwk.Alg) {
+		return nil, nil
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return newPublicKey(jwk.Kid, jwk.Alg, public)
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid coordinate length")
+		}
+		// Validates that the point is on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid P-256 point: %w", err)
+		}
+		public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		return newPublicKey(jwk.Kid, jwk.Alg, public)
+	case "oct":
+		secret, err := decode(jwk.K)
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("invalid symmetric key")
+		}
+		return &key{id: jwk.Kid, algorithm: "HS256", secret: secret}, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,256 @@
+package openapidoc
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/version"
+)
+
+const Version = "3.1.0"
+
+// Name of the path parameter a trailing wildcard of a route pattern is documented as
+const wildcardParam = "path"
+
+const problemContentType = "application/problem+json"
+
+// Schema of the problem details written by routes with the "problem" error format
+var problemSchema = Schema{
+	Type: "object",
+	Properties: map[string]Schema{
+		"type":      {Type: "string"},
+		"title":     {Type: "string"},
+		"status":    {Type: "integer"},
+		"detail":    {Type: "string"},
+		"instance":  {Type: "string"},
+		"route":     {Type: "string"},
+		"requestId": {Type: "string"},
+		"exitCode":  {Type: "integer"},
+	},
+	Required: []string{"type", "title", "status"},
+}
+
+// Generate the OpenAPI document of the routes and groups. The routes are expected to be checked already
+func Generate(routes []config.Route, groups []config.RouteGroup, modules *config.ModulesConfig) *Document {
+	info := modules.OpenAPI
+	if info == nil {
+		info = &config.OpenAPIConfig{}
+	}
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       cmp.Or(info.Title, "webcmd"),
+			Version:     cmp.Or(info.Version, version.Full()),
+			Description: info.Description,
+		},
+		Paths: make(map[string]PathItem),
+	}
+	components := Components{Schemas: make(map[string]Schema), SecuritySchemes: make(map[string]SecurityScheme)}
+
+	addRoute := func(prefix string, route *config.Route) {
+		path, parameters := convertPattern(prefix + route.Route)
+		method := strings.ToLower(route.Method)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		if _, exists := doc.Paths[path][method]; exists {
+			return
+		}
+
+		operation := &Operation{
+			Summary:     route.Summary,
+			Description: route.Description,
+			Tags:        route.Tags,
+			Parameters:  append(parameters, routeParameters(route)...),
+			Responses:   responses(route, modules),
+		}
+		if route.AllowBody || route.Webhook != nil {
+			operation.RequestBody = &RequestBody{Content: map[string]MediaType{"*/*": {}}, Required: route.Webhook != nil}
+		}
+		for _, name := range route.Auth {
+			authenticator, ok := modules.Auth[name]
+			if !ok {
+				continue
+			}
+			components.SecuritySchemes[name] = securityScheme(&authenticator)
+			operation.Security = append(operation.Security, map[string][]string{name: slices.Clone(route.RequireScopes)})
+		}
+		if route.ErrorFormat == config.ErrorFormatProblem {
+			components.Schemas["Problem"] = problemSchema
+		}
+
+		doc.Paths[path][method] = operation
+	}
+
+	for i := range routes {
+		addRoute("", &routes[i])
+	}
+	for _, group := range groups {
+		for i := range group.Routes {
+			addRoute(group.Prefix, &group.Routes[i])
+		}
+	}
+
+	if len(components.Schemas) > 0 || len(components.SecuritySchemes) > 0 {
+		doc.Components = &components
+	}
+	return doc
+}
+
+// Convert a chi route pattern into an OpenAPI path and its path parameters.
+// Regex patterns of parameters become schema patterns and a trailing wildcard becomes the "path" parameter
+func convertPattern(pattern string) (string, []Parameter) {
+	var path strings.Builder
+	var parameters []Parameter
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '{':
+			// Find the closing brace, regex patterns may contain braces themselves
+			depth, end := 0, len(pattern)
+			for j := i; j < len(pattern); j++ {
+				if pattern[j] == '{' {
+					depth++
+				} else if pattern[j] == '}' {
+					if depth--; depth == 0 {
+						end = j
+						break
+					}
+				}
+			}
+			name, regex, _ := strings.Cut(pattern[i+1:end], ":")
+			parameter := Parameter{Name: name, In: "path", Required: true, Schema: Schema{Type: "string"}}
+			if regex != "" {
+				parameter.Schema.Pattern = "^" + regex + "$"
+			}
+			parameters = append(parameters, parameter)
+			path.WriteString("{" + name + "}")
+			i = end
+		case pattern[i] == '*' && i == len(pattern)-1:
+			parameters = append(parameters, Parameter{
+				Name:        wildcardParam,
+				In:          "path",
+				Description: "Remaining path, may contain slashes",
+				Required:    true,
+				Schema:      Schema{Type: "string"},
+			})
+			path.WriteString("{" + wildcardParam + "}")
+		default:
+			path.WriteByte(pattern[i])
+		}
+	}
+
+	// Trailing slashes are stripped on routing
+	result := path.String()
+	if len(result) > 1 {
+		result = strings.TrimSuffix(result, "/")
+	}
+	return cmp.Or(result, "/"), parameters
+}
+
+// Query and header parameters of the route. Route parameters are documented with the path, claims are not part of the request
+func routeParameters(route *config.Route) (result []Parameter) {
+	for _, param := range route.Parameters {
+		var in string
+		switch param.Source {
+		case config.ParamSourceQuery:
+			in = "query"
+		case config.ParamSourceHeader:
+			in = "header"
+		default:
+			continue
+		}
+
+		// Later parameters with the same name overwrite earlier ones
+		result = slices.DeleteFunc(result, func(p Parameter) bool { return p.In == in && p.Name == param.Name })
+		result = append(result, Parameter{Name: param.Name, In: in, Schema: Schema{Type: "string", Default: param.Default}})
+	}
+
+	return
+}
+
+// Possible responses of the route by status code
+func responses(route *config.Route, modules *config.ModulesConfig) map[string]Response {
+	conditions := make(map[int][]string)
+	add := func(status int, condition string) {
+		if _, ok := conditions[status]; !ok {
+			conditions[status] = nil
+		}
+		if condition != "" && !slices.Contains(conditions[status], condition) {
+			conditions[status] = append(conditions[status], condition)
+		}
+	}
+
+	result := make(map[string]Response)
+	switch route.Kind() {
+	case "static":
+		add(route.Static.StatusCode, "")
+	case "redirect":
+		add(route.Redirect.StatusCode, "")
+	case "files":
+		add(http.StatusOK, "")
+		add(http.StatusNotFound, "")
+	case "proxy":
+		result["default"] = Response{Description: "Response of the upstream server"}
+	default:
+		for i := range route.StatusCodes {
+			if mapping := &route.StatusCodes[i]; mapping.StatusCode != 0 {
+				add(mapping.StatusCode, mapping.String())
+			}
+		}
+		add(http.StatusInternalServerError, "command could not be executed")
+	}
+
+	if len(route.Auth) > 0 {
+		add(http.StatusUnauthorized, "not authenticated")
+		if len(route.RequireScopes) > 0 || len(route.RequireClaims) > 0 {
+			add(http.StatusForbidden, "missing scopes or claims")
+		}
+	}
+	if route.Webhook != nil {
+		add(http.StatusUnauthorized, "invalid webhook signature")
+		add(http.StatusRequestEntityTooLarge, "")
+		if route.Webhook.RejectReplays {
+			add(http.StatusConflict, "replayed webhook delivery")
+		}
+	}
+	if len(route.AllowFrom) > 0 || len(route.DenyFrom) > 0 || len(modules.AllowFrom) > 0 || len(modules.DenyFrom) > 0 {
+		add(http.StatusForbidden, "client address not allowed")
+	}
+	if route.RateLimit != nil || modules.RateLimit != nil {
+		add(http.StatusTooManyRequests, "rate limit exceeded")
+	}
+
+	for status, statusConditions := range conditions {
+		description := cmp.Or(http.StatusText(status), fmt.Sprintf("Status code %v", status))
+		if len(statusConditions) > 0 {
+			description += " (" + strings.Join(statusConditions, "; ") + ")"
+		}
+		response := Response{Description: description}
+		if status >= http.StatusBadRequest && route.ErrorFormat == config.ErrorFormatProblem {
+			response.Content = map[string]MediaType{problemContentType: {Schema: &Schema{Ref: "#/components/schemas/Problem"}}}
+		}
+		result[strconv.Itoa(status)] = response
+	}
+
+	return result
+}
+
+// Security scheme of an authenticator
+func securityScheme(authenticator *config.AuthenticatorConfig) SecurityScheme {
+	switch {
+	case authenticator.Basic != nil:
+		return SecurityScheme{Type: "http", Scheme: "basic"}
+	case authenticator.JWT != nil:
+		return SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+	case authenticator.ClientCert != nil:
+		return SecurityScheme{Type: "mutualTLS"}
+	default:
+		return SecurityScheme{Type: "http", Scheme: "bearer"}
+	}
+}
@@ -0,0 +1,94 @@
+package openapidoc
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertPattern(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Pattern        string
+		ExpectedPath   string
+		ExpectedParams map[string]string // Schema patterns by parameter name
+	}{
+		{Name: "root", Pattern: "/", ExpectedPath: "/", ExpectedParams: map[string]string{}},
+		{Name: "trailing slash", Pattern: "/users/", ExpectedPath: "/users", ExpectedParams: map[string]string{}},
+		{Name: "parameter", Pattern: "/users/{id}", ExpectedPath: "/users/{id}", ExpectedParams: map[string]string{"id": ""}},
+		{Name: "regex", Pattern: "/users/{id:[0-9]+}/posts", ExpectedPath: "/users/{id}/posts", ExpectedParams: map[string]string{"id": "^[0-9]+$"}},
+		{Name: "regex with braces", Pattern: "/years/{year:[0-9]{4}}", ExpectedPath: "/years/{year}", ExpectedParams: map[string]string{"year": "^[0-9]{4}$"}},
+		{Name: "wildcard", Pattern: "/files/{bucket}/*", ExpectedPath: "/files/{bucket}/{path}", ExpectedParams: map[string]string{"bucket": "", "path": ""}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			path, parameters := convertPattern(tc.Pattern)
+			assert.Equal(t, tc.ExpectedPath, path)
+
+			params := make(map[string]string)
+			for _, p := range parameters {
+				assert.Equal(t, "path", p.In)
+				assert.True(t, p.Required)
+				params[p.Name] = p.Schema.Pattern
+			}
+			assert.Equal(t, tc.ExpectedParams, params)
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	exitCode := 0
+	modules := config.ModulesConfig{
+		Auth:      map[string]config.AuthenticatorConfig{"tokens": {JWT: &config.AuthJWT{}}},
+		RateLimit: &config.RateLimit{},
+	}
+	routes := []config.Route{
+		{
+			Method:      http.MethodGet,
+			Route:       "/items/{id}",
+			Tags:        []string{"items"},
+			Parameters:  []config.RouteParameter{{Name: "filter", Source: config.ParamSourceQuery}, {Name: "sub", Source: config.ParamSourceClaim}},
+			StatusCodes: []config.ExitCodeMapping{{ExitCode: &exitCode, StatusCode: 200}, {StatusCode: 404}},
+			Auth:        []string{"tokens"},
+			ErrorFormat: config.ErrorFormatProblem,
+		},
+		{Method: http.MethodGet, Route: "/health", Static: &config.RouteStatic{StatusCode: 204}},
+	}
+	groups := []config.RouteGroup{
+		{Prefix: "/api", Routes: []config.Route{{Method: http.MethodPost, Route: "/upload", AllowBody: true, StatusCodes: []config.ExitCodeMapping{{StatusCode: 201}}}}},
+	}
+
+	doc := Generate(routes, groups, &modules)
+
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, "webcmd", doc.Info.Title)
+
+	item := doc.Paths["/items/{id}"]["get"]
+	require.NotNil(t, item)
+	assert.Equal(t, []string{"items"}, item.Tags)
+	require.Len(t, item.Parameters, 2, "claims are not request parameters")
+	assert.Equal(t, "id", item.Parameters[0].Name)
+	assert.Equal(t, "query", item.Parameters[1].In)
+	assert.ElementsMatch(t, []string{"200", "401", "404", "429", "500"}, slices.Collect(maps.Keys(item.Responses)))
+	assert.Contains(t, item.Responses["404"].Content, problemContentType)
+	assert.Equal(t, []map[string][]string{{"tokens": nil}}, item.Security)
+
+	static := doc.Paths["/health"]["get"]
+	require.NotNil(t, static)
+	assert.ElementsMatch(t, []string{"204", "429"}, slices.Collect(maps.Keys(static.Responses)))
+
+	upload := doc.Paths["/api/upload"]["post"]
+	require.NotNil(t, upload)
+	assert.NotNil(t, upload.RequestBody)
+	assert.Contains(t, upload.Responses, "201")
+
+	require.NotNil(t, doc.Components)
+	assert.Equal(t, SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}, doc.Components.SecuritySchemes["tokens"])
+	assert.Contains(t, doc.Components.Schemas, "Problem")
+}
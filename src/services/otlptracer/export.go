@@ -0,0 +1,144 @@
+package otlptracer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/bdoerfchen/webcmd/src/common/version"
+)
+
+// Request body of the OTLP/HTTP JSON encoding. IDs are hex encoded, 64 bit integers are strings
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanJSON `json:"spans"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type spanJSON struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	TraceState        string     `json:"traceState,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"` // 0 unset, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+func valueOf(value any) anyValue {
+	switch v := value.(type) {
+	case string:
+		return anyValue{StringValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return anyValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return anyValue{IntValue: &s}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case bool:
+		return anyValue{BoolValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return anyValue{StringValue: &s}
+	}
+}
+
+// Send the spans to the collector. Failures are logged, the spans are dropped
+func (t *otlpTracer) export(batch []*span) {
+	spans := make([]spanJSON, 0, len(batch))
+	for _, s := range batch {
+		s.mutex.Lock()
+		item := spanJSON{
+			TraceID:           hex.EncodeToString(s.context.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.context.SpanID[:]),
+			TraceState:        s.context.State,
+			Name:              s.name,
+			Kind:              int(s.kind),
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parentID != [8]byte{} {
+			item.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for _, attr := range s.attributes {
+			item.Attributes = append(item.Attributes, keyValue{Key: attr.key, Value: valueOf(attr.value)})
+		}
+		if s.failed {
+			item.Status = status{Code: 2, Message: s.errMessage}
+		}
+		s.mutex.Unlock()
+		spans = append(spans, item)
+	}
+
+	body, err := json.Marshal(exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: []keyValue{{Key: "service.name", Value: valueOf(t.serviceName)}}},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "webcmd", Version: version.Full()}, Spans: spans}},
+	}}})
+	if err != nil {
+		t.logger.Error("failed to encode spans", slog.String("error", err.Error()))
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		t.logger.Error("failed to export spans", slog.String("error", err.Error()))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for header, value := range t.headers {
+		req.Header.Set(header, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.logger.Warn("failed to export spans", slog.String("error", err.Error()), slog.Int("spans", len(spans)))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		t.logger.Warn("collector rejected spans", slog.Int("status", resp.StatusCode), slog.Int("spans", len(spans)))
+	}
+}
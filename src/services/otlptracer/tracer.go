@@ -0,0 +1,185 @@
+package otlptracer
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
+)
+
+// Spans waiting for export. Further spans are dropped when the queue is full
+const queueSize = 2048
+
+// Spans are exported when this many are waiting, or after the interval
+const (
+	batchSize      = 512
+	exportInterval = 5 * time.Second
+)
+
+// A tracer exporting its spans in batches to an OTLP/HTTP collector
+type otlpTracer struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+	logger      *slog.Logger
+
+	queue   chan *span
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func New(tracingConfig *config.TracingConfig, logger *slog.Logger) *otlpTracer {
+	t := &otlpTracer{
+		url:         tracingConfig.TracesURL(),
+		headers:     tracingConfig.Headers,
+		serviceName: tracingConfig.ServiceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      logger,
+		queue:       make(chan *span, queueSize),
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go t.run()
+
+	return t
+}
+
+func (t *otlpTracer) Start(name string, kind tracing.SpanKind, parent tracing.SpanContext) tracing.Span {
+	s := &span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent.IsValid() {
+		s.context = tracing.SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled, State: parent.State}
+		s.parentID = parent.SpanID
+	} else {
+		rand.Read(s.context.TraceID[:])
+		s.context.Sampled = true
+	}
+	rand.Read(s.context.SpanID[:])
+
+	return s
+}
+
+// Export the waiting spans and stop exporting
+func (t *otlpTracer) Shutdown(ctx context.Context) error {
+	close(t.stop)
+	select {
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Collect spans from the queue and export them in batches
+func (t *otlpTracer) run() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*span
+	flush := func() {
+		if len(batch) > 0 {
+			t.export(batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case s := <-t.queue:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case s := <-t.queue:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Hand over an ended span for export, without blocking the request
+func (t *otlpTracer) enqueue(s *span) {
+	select {
+	case t.queue <- s:
+	default:
+		t.logger.Debug("dropped span, export queue is full", slog.String("span", s.name))
+	}
+}
+
+type span struct {
+	tracer   *otlpTracer
+	context  tracing.SpanContext
+	parentID [8]byte
+	kind     tracing.SpanKind
+	start    time.Time
+
+	mutex      sync.Mutex
+	name       string
+	end        time.Time
+	attributes []attribute
+	errMessage string
+	failed     bool
+	ended      bool
+}
+
+type attribute struct {
+	key   string
+	value any
+}
+
+func (s *span) Context() tracing.SpanContext {
+	return s.context
+}
+
+func (s *span) SetName(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.name = name
+}
+
+func (s *span) SetAttribute(key string, value any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attributes = append(s.attributes, attribute{key: key, value: value})
+}
+
+func (s *span) SetError(message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failed = true
+	s.errMessage = message
+}
+
+func (s *span) End() {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mutex.Unlock()
+
+	// Spans of unsampled traces only propagate the trace context
+	if s.context.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
+
+func (s *span) StartChild(name string, kind tracing.SpanKind) tracing.Span {
+	return s.tracer.Start(name, kind, s.context)
+}
@@ -6,6 +6,7 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/bdoerfchen/webcmd/src/common/auth"
 	"github.com/bdoerfchen/webcmd/src/common/config"
 	"github.com/go-chi/chi/v5"
 )
@@ -71,6 +72,8 @@ func (c *ParameterCollection) For(r *http.Request) map[string]string {
 			value = r.URL.Query().Get(param.Name)
 		case config.ParamSourceRoute:
 			value = chi.URLParam(r, param.Name)
+		case config.ParamSourceClaim:
+			value, _ = auth.PrincipalFromContext(r.Context()).Claim(param.Name)
 		}
 
 		if value != "" {
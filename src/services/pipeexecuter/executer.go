@@ -0,0 +1,108 @@
+package pipeexecuter
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/process"
+)
+
+// An executer running a chain of processes, each stdout connected to the next stdin with os pipes
+type pipeExecuter struct{}
+
+func New() *pipeExecuter {
+	return &pipeExecuter{}
+}
+
+func (e *pipeExecuter) Execute(ctx context.Context, config execution.Config) (proc *process.Process, exitCode int, err error) {
+	steps := config.Exec.Pipeline
+	if len(steps) == 0 {
+		return nil, 0, fmt.Errorf("missing pipeline config")
+	}
+
+	// Prepare commands. Stderr of all steps is aggregated, only the last stdout is kept
+	proc = &process.Process{}
+	stdout, stderr := proc.Writers()
+	cmds := make([]*exec.Cmd, len(steps))
+	for i, step := range steps {
+		cmd := exec.Command(step.Path, step.Args...)
+		process.SetGroup(cmd)
+		for key, value := range config.Env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+		}
+		cmd.Stderr = stderr
+		cmds[i] = cmd
+	}
+	cmds[0].Stdin = config.Stdin
+	cmds[len(cmds)-1].Stdout = stdout
+
+	// Connect steps with pipes
+	pipes := make([]io.Closer, 0, 2*(len(cmds)-1))
+	defer func() {
+		for _, pipe := range pipes {
+			pipe.Close()
+		}
+	}()
+	for i := range len(cmds) - 1 {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to create pipe: %w", err)
+		}
+		pipes = append(pipes, reader, writer)
+		cmds[i].Stdout = writer
+		cmds[i+1].Stdin = reader
+	}
+
+	// Start all steps
+	for i, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			for _, started := range cmds[:i] {
+				started.Process.Kill()
+				started.Wait()
+			}
+			return nil, 0, fmt.Errorf("unable to start pipeline step %v: %w", i+1, err)
+		}
+	}
+	for _, cmd := range cmds {
+		config.ReportStarted(cmd.Process)
+	}
+	// Close the pipe ends of this process, so steps receive EOF once their predecessor exits
+	for _, pipe := range pipes {
+		pipe.Close()
+	}
+	pipes = nil
+
+	// Wait for all steps. The first failing step defines the exit code (pipefail)
+	var failed *exec.Cmd
+	var waitErr error
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			if _, isExitErr := err.(*exec.ExitError); !isExitErr {
+				waitErr = cmp.Or(waitErr, fmt.Errorf("error during execution of pipeline step %v: %w", i+1, err))
+			} else if failed == nil {
+				failed = cmd
+			}
+		}
+	}
+	if waitErr != nil {
+		return nil, -1, waitErr
+	}
+
+	if failed != nil {
+		proc.Proc = failed
+		proc.Signal = process.SignalOf(failed)
+		return proc, failed.ProcessState.ExitCode(), nil
+	}
+
+	proc.Proc = cmds[len(cmds)-1]
+	return proc, 0, nil
+}
+
+func (e *pipeExecuter) Describe() (mode execution.ExecMode, attributes []any) {
+	return execution.ModePipeline, []any{}
+}
@@ -0,0 +1,120 @@
+package pipeexecuter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bash(command string) config.ExecProc {
+	return config.ExecProc{Path: "bash", Args: []string{"-c", command}}
+}
+
+func execConfig(steps ...config.ExecProc) execution.Config {
+	route := config.DefaultRoute()
+	route.Exec.Pipeline = steps
+	return execution.ConfigFromRoute(&route)
+}
+
+func TestExecutePipeline(t *testing.T) {
+	execConfig := execConfig(
+		bash("cat -; echo $WC_NAME"),
+		bash("sort"),
+		bash("tr a-z A-Z"),
+	)
+	execConfig.Stdin = strings.NewReader("b\nc\n")
+	execConfig.Env = map[string]string{"WC_NAME": "a"}
+
+	proc, exitCode, err := New().Execute(context.Background(), execConfig)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "A\nB\nC\n", proc.StdOut.String())
+	assert.Empty(t, proc.StdErr.String())
+}
+
+func TestExecuteExitCodes(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Steps            []config.ExecProc
+		ExpectedExitCode int
+		ExpectedStdout   string
+	}{
+		{Name: "success", Steps: []config.ExecProc{bash("echo ok"), bash("cat -")}, ExpectedExitCode: 0, ExpectedStdout: "ok\n"},
+		{Name: "last fails", Steps: []config.ExecProc{bash("echo ok"), bash("cat -; exit 4")}, ExpectedExitCode: 4, ExpectedStdout: "ok\n"},
+		{Name: "first fails", Steps: []config.ExecProc{bash("echo ok; exit 2"), bash("cat -")}, ExpectedExitCode: 2, ExpectedStdout: "ok\n"},
+		{Name: "first failing step wins", Steps: []config.ExecProc{bash("exit 0"), bash("exit 3"), bash("exit 5")}, ExpectedExitCode: 3, ExpectedStdout: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			proc, exitCode, err := New().Execute(context.Background(), execConfig(tc.Steps...))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.ExpectedExitCode, exitCode)
+			assert.Equal(t, tc.ExpectedStdout, proc.StdOut.String())
+		})
+	}
+}
+
+func TestExecuteAggregatesStderr(t *testing.T) {
+	proc, _, err := New().Execute(context.Background(), execConfig(
+		bash("echo first >&2; echo out"),
+		bash("cat - >/dev/null; echo second >&2"),
+	))
+	require.NoError(t, err)
+
+	assert.Empty(t, proc.StdOut.String())
+	assert.ElementsMatch(t, []string{"first", "second"}, strings.Fields(proc.StdErr.String()))
+}
+
+func TestExecuteMissingExecutable(t *testing.T) {
+	_, _, err := New().Execute(context.Background(), execConfig(
+		bash("echo out"),
+		config.ExecProc{Path: "/does/not/exist"},
+	))
+	assert.Error(t, err)
+}
+
+func TestTerminate(t *testing.T) {
+	tracker := execution.NewTracker()
+	finished := make(chan struct{})
+	start := func(command string) {
+		running := tracker.Start("GET /", "")
+		execConfig := execConfig(bash(command))
+		execConfig.Started = func(process *os.Process) { tracker.AddProcess(running, process) }
+		go func() {
+			defer func() { finished <- struct{}{} }()
+			defer tracker.Finish(running)
+			New().Execute(context.Background(), execConfig)
+		}()
+	}
+	ready := filepath.Join(t.TempDir(), "ready")
+	start("sleep 10")
+	start("trap '' TERM; touch " + ready + "; sleep 10")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(ready)
+		return err == nil && len(tracker.List()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// The execution ignoring SIGTERM is killed after the grace period
+	startTime := time.Now()
+	assert.Equal(t, 1, tracker.Terminate(300*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(startTime), 300*time.Millisecond)
+	for range 2 {
+		select {
+		case <-finished:
+		case <-time.After(2 * time.Second):
+			t.Fatal("execution did not finish")
+		}
+	}
+	assert.Zero(t, tracker.Count())
+}
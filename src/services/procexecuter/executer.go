@@ -35,8 +35,13 @@ func (e *procExecuter) Execute(ctx context.Context, config execution.Config) (pr
 	}
 
 	// Start and wait for command to finish
-	if err := cmd.Proc.Run(); err != nil {
+	if err := cmd.Proc.Start(); err != nil {
+		return nil, -1, fmt.Errorf("error during command execution: %w", err)
+	}
+	config.ReportStarted(cmd.Proc.Process)
+	if err := cmd.Proc.Wait(); err != nil {
 		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr {
+			cmd.Signal = process.SignalOf(cmd.Proc)
 			return cmd, exitErr.ExitCode(), nil
 		}
 
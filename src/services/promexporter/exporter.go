@@ -0,0 +1,216 @@
+package promexporter
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Upper bounds of the duration histogram buckets in seconds
+var buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// A metrics recorder exposing its metrics in the Prometheus text format
+type exporter struct {
+	mutex              sync.Mutex
+	requests           map[requestLabels]*histogram
+	executions         map[executionLabels]uint64
+	executionDurations map[string]*histogram // Execution durations by route
+	gauges             []gauge
+
+	inFlight    atomic.Int64
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+}
+
+type requestLabels struct {
+	route  string
+	method string
+	status int
+}
+
+type executionLabels struct {
+	route    string
+	exitCode string
+}
+
+type gauge struct {
+	name  string
+	help  string
+	value func() float64
+}
+
+type histogram struct {
+	counts []uint64 // Non-cumulative counts per bucket, with the last one for values above all bounds
+	sum    float64
+	count  uint64
+}
+
+func New() *exporter {
+	return &exporter{
+		requests:           make(map[requestLabels]*histogram),
+		executions:         make(map[executionLabels]uint64),
+		executionDurations: make(map[string]*histogram),
+	}
+}
+
+func (e *exporter) ObserveRequest(route, method string, status int, duration time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	labels := requestLabels{route: route, method: method, status: status}
+	if e.requests[labels] == nil {
+		e.requests[labels] = newHistogram()
+	}
+	e.requests[labels].observe(duration.Seconds())
+}
+
+func (e *exporter) ExecutionStarted() {
+	e.inFlight.Add(1)
+}
+
+func (e *exporter) ExecutionFinished(route, exitCode string, duration time.Duration) {
+	e.inFlight.Add(-1)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.executions[executionLabels{route: route, exitCode: exitCode}]++
+	if e.executionDurations[route] == nil {
+		e.executionDurations[route] = newHistogram()
+	}
+	e.executionDurations[route].observe(duration.Seconds())
+}
+
+func (e *exporter) ObserveCache(hit bool) {
+	if hit {
+		e.cacheHits.Add(1)
+	} else {
+		e.cacheMisses.Add(1)
+	}
+}
+
+func (e *exporter) AddGauge(name, help string, value func() float64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.gauges = append(e.gauges, gauge{name: name, help: help, value: value})
+}
+
+func (e *exporter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		e.WriteTo(w)
+	})
+}
+
+// Write all metrics in the Prometheus text exposition format
+func (e *exporter) WriteTo(w io.Writer) (int64, error) {
+	var out strings.Builder
+
+	e.mutex.Lock()
+	requestKeys := slices.SortedFunc(maps.Keys(e.requests), func(a, b requestLabels) int {
+		return cmp.Or(strings.Compare(a.route, b.route), strings.Compare(a.method, b.method), cmp.Compare(a.status, b.status))
+	})
+	writeHeader(&out, "webcmd_http_requests_total", "counter", "Number of finished HTTP requests by route pattern, method and status code")
+	for _, key := range requestKeys {
+		writeSample(&out, "webcmd_http_requests_total", key.labels(), float64(e.requests[key].count))
+	}
+	writeHeader(&out, "webcmd_http_request_duration_seconds", "histogram", "Duration of HTTP requests by route pattern, method and status code")
+	for _, key := range requestKeys {
+		e.requests[key].write(&out, "webcmd_http_request_duration_seconds", key.labels())
+	}
+
+	executionKeys := slices.SortedFunc(maps.Keys(e.executions), func(a, b executionLabels) int {
+		return cmp.Or(strings.Compare(a.route, b.route), strings.Compare(a.exitCode, b.exitCode))
+	})
+	writeHeader(&out, "webcmd_executions_total", "counter", "Number of finished executions by route pattern and exit code")
+	for _, key := range executionKeys {
+		writeSample(&out, "webcmd_executions_total", []string{"route", key.route, "exit_code", key.exitCode}, float64(e.executions[key]))
+	}
+	writeHeader(&out, "webcmd_execution_duration_seconds", "histogram", "Duration of executions by route pattern")
+	for _, route := range slices.Sorted(maps.Keys(e.executionDurations)) {
+		e.executionDurations[route].write(&out, "webcmd_execution_duration_seconds", []string{"route", route})
+	}
+	gauges := slices.Clone(e.gauges)
+	e.mutex.Unlock()
+
+	writeHeader(&out, "webcmd_executions_in_flight", "gauge", "Number of executions currently running")
+	writeSample(&out, "webcmd_executions_in_flight", nil, float64(e.inFlight.Load()))
+	writeHeader(&out, "webcmd_cache_hits_total", "counter", "Number of requests answered from cache")
+	writeSample(&out, "webcmd_cache_hits_total", nil, float64(e.cacheHits.Load()))
+	writeHeader(&out, "webcmd_cache_misses_total", "counter", "Number of requests to cached routes that were not answered from cache")
+	writeSample(&out, "webcmd_cache_misses_total", nil, float64(e.cacheMisses.Load()))
+
+	for _, gauge := range gauges {
+		writeHeader(&out, gauge.name, "gauge", gauge.help)
+		writeSample(&out, gauge.name, nil, gauge.value())
+	}
+
+	n, err := io.WriteString(w, out.String())
+	return int64(n), err
+}
+
+func (l requestLabels) labels() []string {
+	return []string{"route", l.route, "method", l.method, "status", strconv.Itoa(l.status)}
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(value float64) {
+	i, _ := slices.BinarySearch(buckets, value)
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// Write the cumulative buckets, sum and count of the histogram
+func (h *histogram) write(out *strings.Builder, name string, labels []string) {
+	var cumulative uint64
+	for i, bound := range buckets {
+		cumulative += h.counts[i]
+		writeSample(out, name+"_bucket", append(slices.Clip(labels), "le", formatFloat(bound)), float64(cumulative))
+	}
+	writeSample(out, name+"_bucket", append(slices.Clip(labels), "le", "+Inf"), float64(h.count))
+	writeSample(out, name+"_sum", labels, h.sum)
+	writeSample(out, name+"_count", labels, float64(h.count))
+}
+
+func writeHeader(out *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Write a sample line. Labels are given as name and value pairs
+func writeSample(out *strings.Builder, name string, labels []string, value float64) {
+	out.WriteString(name)
+	if len(labels) > 0 {
+		out.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			fmt.Fprintf(out, `%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		out.WriteByte('}')
+	}
+	out.WriteByte(' ')
+	out.WriteString(formatFloat(value))
+	out.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,51 @@
+package promexporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExposition(t *testing.T) {
+	exporter := New()
+	exporter.ObserveRequest("/items/{id}", http.MethodGet, 200, 20*time.Millisecond)
+	exporter.ObserveRequest("/items/{id}", http.MethodGet, 200, 2*time.Second)
+	exporter.ObserveRequest(`/"quoted"`, http.MethodPost, 404, time.Millisecond)
+	exporter.ExecutionStarted()
+	exporter.ExecutionStarted()
+	exporter.ExecutionFinished("/items/{id}", "0", 10*time.Millisecond)
+	exporter.ObserveCache(true)
+	exporter.ObserveCache(false)
+	exporter.ObserveCache(false)
+	exporter.AddGauge("webcmd_test", "Test gauge", func() float64 { return 1.5 })
+
+	recorder := httptest.NewRecorder()
+	exporter.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, contentType, recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE webcmd_http_requests_total counter",
+		`webcmd_http_requests_total{route="/\"quoted\"",method="POST",status="404"} 1`,
+		`webcmd_http_requests_total{route="/items/{id}",method="GET",status="200"} 2`,
+		"# TYPE webcmd_http_request_duration_seconds histogram",
+		`webcmd_http_request_duration_seconds_bucket{route="/items/{id}",method="GET",status="200",le="0.01"} 0`,
+		`webcmd_http_request_duration_seconds_bucket{route="/items/{id}",method="GET",status="200",le="0.025"} 1`,
+		`webcmd_http_request_duration_seconds_bucket{route="/items/{id}",method="GET",status="200",le="2.5"} 2`,
+		`webcmd_http_request_duration_seconds_bucket{route="/items/{id}",method="GET",status="200",le="+Inf"} 2`,
+		`webcmd_http_request_duration_seconds_sum{route="/items/{id}",method="GET",status="200"} 2.02`,
+		`webcmd_http_request_duration_seconds_count{route="/items/{id}",method="GET",status="200"} 2`,
+		`webcmd_executions_total{route="/items/{id}",exit_code="0"} 1`,
+		`webcmd_execution_duration_seconds_count{route="/items/{id}"} 1`,
+		"webcmd_executions_in_flight 1",
+		"webcmd_cache_hits_total 1",
+		"webcmd_cache_misses_total 2",
+		"# HELP webcmd_test Test gauge",
+		"webcmd_test 1.5",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
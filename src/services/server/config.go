@@ -1,6 +1,93 @@
 package server
 
+import (
+	"fmt"
+
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+)
+
 type Config struct {
-	Host string
-	Port uint16
+	Host         string
+	Port         uint16
+	Socket       string            // Path of a unix socket to listen on instead of host and port
+	Listeners    []ListenerConfig  // Listeners to serve on, replacing host, port and socket
+	TLS          *TLSConfig        // Serve HTTPS instead of HTTP
+	ClientAuth   *ClientAuthConfig // Verify client certificates. Requires TLS
+	DrainTimeout timem.Duration    // Time requests in flight may take to finish on shutdown. Zero waits indefinitely
+
+	// Timeouts and limits, zero disables them
+	ReadHeaderTimeout timem.Duration // Time to read the request headers
+	ReadTimeout       timem.Duration // Time to read the entire request, including the body
+	WriteTimeout      timem.Duration // Time from the end of the request headers to the end of the response
+	IdleTimeout       timem.Duration // Time to wait for the next request on a keep-alive connection
+	MaxHeaderBytes    int            // Maximum size of the request headers
+	MaxBodyBytes      int64          // Maximum size of request bodies, larger ones are answered with 413 Request Entity Too Large
+
+	H2C bool // Serve HTTP/2 without TLS (prior knowledge), in addition to HTTP/1
+}
+
+// A listener of the server. Routes can be restricted to listeners by name
+type ListenerConfig struct {
+	Name   string // Name routes refer to. Defaults to the address
+	Type   string // "tcp" (default), "unix" or "systemd"
+	Host   string // Host of a tcp listener. All interfaces by default
+	Port   uint16 // Port of a tcp listener
+	Path   string // Path of a unix socket
+	Mode   string // Octal file mode of a unix socket, "0600" by default
+	Owner  string // Owner of a unix socket as "user" or "user:group"
+	FDName string // Name of the systemd socket (from LISTEN_FDNAMES). All passed sockets without name
+
+	TrustForwarded bool // Trust the forwarded client address of all clients, like of trusted proxies. For the socket of a local reverse proxy
+}
+
+// The configured listeners, or the listener of host and port or socket if there are none
+func (c *Config) EffectiveListeners() []ListenerConfig {
+	if len(c.Listeners) > 0 {
+		return c.Listeners
+	}
+	if c.Socket != "" {
+		return []ListenerConfig{{Type: ListenerUnix, Path: c.Socket}}
+	}
+	return []ListenerConfig{{Type: ListenerTCP, Host: c.Host, Port: c.Port}}
+}
+
+// Address of the listener (like 0.0.0.0:8080, unix:/run/webcmd.sock or systemd:web)
+func (l *ListenerConfig) Address() string {
+	switch l.Type {
+	case ListenerUnix:
+		return "unix:" + l.Path
+	case ListenerSystemd:
+		return "systemd:" + l.FDName
+	default:
+		return fmt.Sprintf("%s:%v", l.Host, l.Port)
+	}
+}
+
+type TLSConfig struct {
+	CertFile     string // Path of the PEM encoded certificate chain
+	KeyFile      string // Path of the PEM encoded private key
+	MinVersion   string // Minimum TLS version, "1.2" (default) or "1.3"
+	CipherPolicy string // "default" for the Go defaults, or "modern" to allow only AEAD cipher suites with forward secrecy for TLS 1.2
+}
+
+type ClientAuthConfig struct {
+	CAFile string // Path of the PEM encoded CA bundle to verify client certificates with
+	Mode   string // "required" (default) rejects connections without valid certificate, "optional" only verifies certificates that are presented
 }
+
+const (
+	ListenerTCP     = "tcp"
+	ListenerUnix    = "unix"
+	ListenerSystemd = "systemd"
+
+	DefaultSocketMode = "0600"
+
+	TLSVersion12 = "1.2"
+	TLSVersion13 = "1.3"
+
+	CipherPolicyDefault = "default"
+	CipherPolicyModern  = "modern"
+
+	ClientAuthRequired = "required"
+	ClientAuthOptional = "optional"
+)
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type listenerKey struct{}
+
+// Add the name of the listener a request was received on to its context
+func WithListener(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, listenerKey{}, name)
+}
+
+// Name of the listener the request was received on. Empty for the listener of host and port or socket
+func ListenerFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(listenerKey{}).(string)
+	return name
+}
+
+type trustForwardedKey struct{}
+
+// Mark the requests of the context as received on a listener whose clients may forward the client address
+func WithTrustedForwarding(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustForwardedKey{}, true)
+}
+
+// Returns true if the request was received on a listener whose clients may forward the client address
+func TrustsForwarded(ctx context.Context) bool {
+	trusted, _ := ctx.Value(trustForwardedKey{}).(bool)
+	return trusted
+}
+
+// Open the sockets of a listener. Systemd listeners may have several
+func listen(config *ListenerConfig) ([]net.Listener, error) {
+	switch config.Type {
+	case ListenerUnix:
+		listener, err := listenUnix(config)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{listener}, nil
+	case ListenerSystemd:
+		return systemdListeners(config.FDName)
+	default:
+		listener, err := net.Listen("tcp", config.Address())
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{listener}, nil
+	}
+}
+
+func listenUnix(config *ListenerConfig) (net.Listener, error) {
+	// Remove the socket left behind by a previous process, but no other files
+	if info, err := os.Stat(config.Path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(config.Path)
+	}
+	listener, err := net.Listen("unix", config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the user running webcmd may connect by default
+	mode, err := strconv.ParseUint(cmp.Or(config.Mode, DefaultSocketMode), 8, 32)
+	if err == nil {
+		err = os.Chmod(config.Path, os.FileMode(mode))
+	}
+	if err == nil && config.Owner != "" {
+		var uid, gid int
+		if uid, gid, err = LookupOwner(config.Owner); err == nil {
+			err = os.Chown(config.Path, uid, gid)
+		}
+	}
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set up socket %s: %w", config.Path, err)
+	}
+	return listener, nil
+}
+
+// User and group id of an owner given as "user" or "user:group". Without group, the id of the group is -1 to keep it
+func LookupOwner(owner string) (uid int, gid int, err error) {
+	name, groupName, hasGroup := strings.Cut(owner, ":")
+	account, err := user.Lookup(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	if uid, err = strconv.Atoi(account.Uid); err != nil {
+		return 0, 0, fmt.Errorf("user %s has no numeric id", name)
+	}
+	if !hasGroup {
+		return uid, -1, nil
+	}
+
+	group, err := user.LookupGroup(groupName)
+	if err != nil {
+		return 0, 0, err
+	}
+	if gid, err = strconv.Atoi(group.Gid); err != nil {
+		return 0, 0, fmt.Errorf("group %s has no numeric id", groupName)
+	}
+	return uid, gid, nil
+}
+
+// Sockets passed by systemd socket activation, by their name. They are only taken once from the environment
+var systemd struct {
+	once    sync.Once
+	sockets []systemdSocket
+	err     error
+}
+
+type systemdSocket struct {
+	name     string
+	listener net.Listener
+}
+
+// First file descriptor passed by systemd
+const listenFDsStart = 3
+
+// Whether the process was started by systemd socket activation
+func SocketActivated() bool {
+	return os.Getenv("LISTEN_PID") == strconv.Itoa(os.Getpid()) && os.Getenv("LISTEN_FDS") != ""
+}
+
+// Listeners of the sockets passed by systemd with the name, or of all passed sockets without name
+func systemdListeners(name string) ([]net.Listener, error) {
+	systemd.once.Do(func() {
+		if !SocketActivated() {
+			systemd.err = fmt.Errorf("no sockets passed by systemd (LISTEN_FDS and LISTEN_PID are not set)")
+			return
+		}
+		count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil {
+			systemd.err = fmt.Errorf("invalid LISTEN_FDS: %w", err)
+			return
+		}
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+		// Executed commands must not take the sockets for their own
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+
+		for i := range count {
+			socketName := ""
+			if i < len(names) {
+				socketName = names[i]
+			}
+			file := os.NewFile(uintptr(listenFDsStart+i), socketName)
+			listener, err := net.FileListener(file)
+			file.Close()
+			if err != nil {
+				systemd.err = fmt.Errorf("socket %v passed by systemd is no listener: %w", i, err)
+				return
+			}
+			systemd.sockets = append(systemd.sockets, systemdSocket{name: socketName, listener: listener})
+		}
+	})
+	if systemd.err != nil {
+		return nil, systemd.err
+	}
+
+	var result []net.Listener
+	for _, socket := range systemd.sockets {
+		if name == "" || socket.name == name {
+			result = append(result, socket.listener)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no socket named '%s' passed by systemd", name)
+	}
+	return result, nil
+}
@@ -2,43 +2,217 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bdoerfchen/webcmd/src/logging"
 )
 
+// Returned by Run if requests were still in flight when the drain timeout expired
+var ErrDrainTimeout = errors.New("drain timed out")
+
 type server struct {
 	config Config
+	tls    *tlsReloader
 }
 
-func New(config Config) *server {
-	return &server{
+func New(config Config) (*server, error) {
+	s := &server{
 		config: config,
 	}
+
+	if config.TLS != nil {
+		reloader, err := newTLSReloader(config)
+		if err != nil {
+			return nil, err
+		}
+		s.tls = reloader
+	}
+
+	return s, nil
 }
 
 func (s *server) Run(ctx context.Context, handler http.Handler) error {
-	// Logger setup
-	host := fmt.Sprintf("%s:%v", s.config.Host, s.config.Port)
 	logger := logging.FromContext(ctx)
-	logger.Info(fmt.Sprintf("listening on %s", host))
-
-	// Setup
-	server := http.Server{
-		Addr:        host,
-		Handler:     handler,
-		ReadTimeout: 5 * time.Second,
-	}
-	go func() {
-		// Goroutine watching the context cancellation in parallel
-		<-ctx.Done()
-		// ...and shutting down the server
-		logger.Error("context cancelled")
-		server.Shutdown(ctx)
-	}()
-
-	// Listen
-	return server.ListenAndServe()
+	scheme := "http"
+	if s.tls != nil {
+		scheme = "https"
+	} else if s.config.H2C {
+		scheme += ", h2c"
+	}
+
+	// Open all listeners before serving, so the server either serves on all of them or fails
+	type listenerServer struct {
+		config    ListenerConfig
+		server    *http.Server
+		listeners []net.Listener
+	}
+	var servers []listenerServer
+	closeAll := func() {
+		for _, ls := range servers {
+			for _, listener := range ls.listeners {
+				listener.Close()
+			}
+		}
+	}
+	if s.config.MaxBodyBytes > 0 {
+		handler = limitBody(handler, s.config.MaxBodyBytes)
+	}
+	for _, config := range s.config.EffectiveListeners() {
+		listeners, err := listen(&config)
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("failed to listen on %s: %w", config.Address(), err)
+		}
+		servers = append(servers, listenerServer{config: config, server: s.httpServer(handler, &config), listeners: listeners})
+	}
+
+	// Serve until a listener fails or the context is cancelled
+	served := make(chan error, 1)
+	for _, ls := range servers {
+		for _, listener := range ls.listeners {
+			attributes := []any{}
+			if ls.config.Name != "" && ls.config.Name != ls.config.Address() {
+				attributes = append(attributes, slog.String("listener", ls.config.Name))
+			}
+			logger.Info(fmt.Sprintf("listening on %s (%s)", displayAddress(&ls.config, listener), scheme), attributes...)
+			go func() {
+				var err error
+				if s.tls != nil {
+					err = ls.server.ServeTLS(listener, "", "")
+				} else {
+					err = ls.server.Serve(listener)
+				}
+				select {
+				case served <- err:
+				default:
+				}
+			}()
+		}
+	}
+	logger.Info("server limits", s.limits()...)
+	var err error
+	select {
+	case err = <-served:
+	case <-ctx.Done():
+	}
+
+	// Stop accepting connections and wait for the requests in flight
+	if err == nil {
+		logger.Info("draining requests", slog.Duration("timeout", time.Duration(s.config.DrainTimeout)))
+	}
+	drainCtx := context.Background()
+	if s.config.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithTimeout(drainCtx, time.Duration(s.config.DrainTimeout))
+		defer cancel()
+	}
+	var wait sync.WaitGroup
+	var timedOut atomic.Bool
+	for _, ls := range servers {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if ls.server.Shutdown(drainCtx) != nil {
+				timedOut.Store(true)
+			}
+		}()
+	}
+	wait.Wait()
+
+	if err != nil {
+		return err
+	}
+	if timedOut.Load() {
+		return ErrDrainTimeout
+	}
+	return nil
+}
+
+// HTTP server for the listener, whose name and the body limit are added to the context of its requests
+func (s *server) httpServer(handler http.Handler, listener *ListenerConfig) *http.Server {
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(s.config.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(s.config.ReadTimeout),
+		WriteTimeout:      time.Duration(s.config.WriteTimeout),
+		IdleTimeout:       time.Duration(s.config.IdleTimeout),
+		MaxHeaderBytes:    s.config.MaxHeaderBytes,
+		BaseContext: func(net.Listener) context.Context {
+			ctx := WithListener(context.Background(), listener.Name)
+			if listener.TrustForwarded {
+				ctx = WithTrustedForwarding(ctx)
+			}
+			if s.config.MaxBodyBytes > 0 {
+				ctx = WithBodyLimit(ctx, s.config.MaxBodyBytes)
+			}
+			return ctx
+		},
+	}
+	if s.tls != nil {
+		server.TLSConfig = s.tls.TLSConfig()
+	} else if s.config.H2C {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	return server
+}
+
+// Address of the listener for logs. Sockets passed by systemd are shown with their local address
+func displayAddress(config *ListenerConfig, listener net.Listener) string {
+	address := config.Address()
+	if config.Type == ListenerSystemd {
+		address += " " + listener.Addr().String()
+	}
+	return address
+}
+
+// Timeouts and limits as log attributes, zero values are disabled
+func (s *server) limits() []any {
+	return []any{
+		slog.Duration("readHeaderTimeout", time.Duration(s.config.ReadHeaderTimeout)),
+		slog.Duration("readTimeout", time.Duration(s.config.ReadTimeout)),
+		slog.Duration("writeTimeout", time.Duration(s.config.WriteTimeout)),
+		slog.Duration("idleTimeout", time.Duration(s.config.IdleTimeout)),
+		slog.Int("maxHeaderBytes", s.config.MaxHeaderBytes),
+		slog.Int64("maxBodyBytes", s.config.MaxBodyBytes),
+	}
+}
+
+// Stop reading bodies at the limit. Requests with a larger declared body are answered by the router, which knows the error format of the route
+func limitBody(handler http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.Body = http.MaxBytesReader(w, req.Body, limit)
+		handler.ServeHTTP(w, req)
+	})
+}
+
+type bodyLimitKey struct{}
+
+// Add the maximum size of request bodies to the context
+func WithBodyLimit(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, bodyLimitKey{}, limit)
+}
+
+// Maximum size of request bodies of the server the request was received on. Zero if bodies are not limited
+func BodyLimitFromContext(ctx context.Context) int64 {
+	limit, _ := ctx.Value(bodyLimitKey{}).(int64)
+	return limit
+}
+
+// Reload the certificate and client CAs from their files, if TLS is enabled
+func (s *server) ReloadTLS() error {
+	if s.tls == nil {
+		return nil
+	}
+
+	return s.tls.Reload()
 }
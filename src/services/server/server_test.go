@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitBody(t *testing.T) {
+	handler := limitBody(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := io.ReadAll(req.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}), 4)
+
+	testCases := []struct {
+		Name          string
+		Body          string
+		ContentLength int64
+		Status        int
+	}{
+		{Name: "within limit", Body: "abcd", ContentLength: 4, Status: http.StatusOK},
+		{Name: "chunked too large", Body: "abcde", ContentLength: -1, Status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.Body))
+			req.ContentLength = tc.ContentLength
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.Status, recorder.Code)
+		})
+	}
+}
+
+func TestRunDrain(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		RequestTime   time.Duration
+		ExpectedError error
+	}{
+		{Name: "request finished", RequestTime: 50 * time.Millisecond},
+		{Name: "request still in flight", RequestTime: time.Second, ExpectedError: ErrDrainTimeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "webcmd.sock")
+			s, err := New(Config{Listeners: []ListenerConfig{{Type: ListenerUnix, Path: path}}, DrainTimeout: timem.Duration(300 * time.Millisecond)})
+			require.NoError(t, err)
+
+			started := make(chan struct{})
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				close(started)
+				time.Sleep(tc.RequestTime)
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			result := make(chan error, 1)
+			go func() { result <- s.Run(ctx, handler) }()
+
+			client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			}}}
+			require.Eventually(t, func() bool {
+				conn, err := net.Dial("unix", path)
+				if err != nil {
+					return false
+				}
+				conn.Close()
+				return true
+			}, time.Second, 10*time.Millisecond)
+			go client.Get("http://webcmd/")
+
+			// Shut down while the request is in flight
+			<-started
+			cancel()
+			select {
+			case err := <-result:
+				if tc.ExpectedError != nil {
+					assert.ErrorIs(t, err, tc.ExpectedError)
+				} else {
+					assert.NoError(t, err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("server did not stop")
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// Cipher suites of the modern policy for TLS 1.2. TLS 1.3 suites are not configurable
+var modernCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Provides the tls config with the current certificate and client CAs, which can be reloaded from their files
+type tlsReloader struct {
+	config      Config
+	base        *tls.Config
+	certificate atomic.Pointer[tls.Certificate]
+	clientCAs   atomic.Pointer[x509.CertPool]
+}
+
+func newTLSReloader(config Config) (*tlsReloader, error) {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The config is replaced per connection, so it has to offer the protocols the http server would add itself
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if config.TLS.MinVersion == TLSVersion13 {
+		base.MinVersion = tls.VersionTLS13
+	}
+	if config.TLS.CipherPolicy == CipherPolicyModern {
+		base.CipherSuites = modernCipherSuites
+	}
+	if config.ClientAuth != nil {
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+		if config.ClientAuth.Mode == ClientAuthOptional {
+			base.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	reloader := &tlsReloader{config: config, base: base}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Load certificate and client CAs from their files. On error, the previous ones stay in use
+func (r *tlsReloader) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(r.config.TLS.CertFile, r.config.TLS.KeyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.config.ClientAuth != nil {
+		content, err := os.ReadFile(r.config.ClientAuth.CAFile)
+		if err != nil {
+			return fmt.Errorf("unable to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(content) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.config.ClientAuth.CAFile)
+		}
+	}
+
+	r.certificate.Store(&certificate)
+	r.clientCAs.Store(clientCAs)
+	return nil
+}
+
+// Returns the tls config used by the server, which always uses the current certificate and client CAs
+func (r *tlsReloader) TLSConfig() *tls.Config {
+	config := r.base.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		current := r.base.Clone()
+		current.Certificates = []tls.Certificate{*r.certificate.Load()}
+		current.ClientCAs = r.clientCAs.Load()
+		return current, nil
+	}
+	return config
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Create a certificate signed by the parent, or a self-signed CA without parent
+func createCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return certificate, key
+}
+
+// Write certificate and key as PEM files
+func writePEM(t *testing.T, certPath string, keyPath string, certificate *x509.Certificate, key *ecdsa.PrivateKey) {
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}), 0o600))
+	if key != nil {
+		der, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	directory := t.TempDir()
+	certPath, keyPath, caPath := filepath.Join(directory, "cert.pem"), filepath.Join(directory, "key.pem"), filepath.Join(directory, "ca.pem")
+
+	ca, caKey := createCertificate(t, "ca", nil, nil)
+	writePEM(t, caPath, "", ca, nil)
+	first, firstKey := createCertificate(t, "first", ca, caKey)
+	writePEM(t, certPath, keyPath, first, firstKey)
+	client, clientKey := createCertificate(t, "client", ca, caKey)
+
+	reloader, err := newTLSReloader(Config{
+		TLS:        &TLSConfig{CertFile: certPath, KeyFile: keyPath, MinVersion: TLSVersion12},
+		ClientAuth: &ClientAuthConfig{CAFile: caPath, Mode: ClientAuthRequired},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.TLS.VerifiedChains[0][0].Subject.CommonName))
+	}))
+	server.TLS = reloader.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	// Returns the common name of the server certificate and the response body
+	request := func(withClientCert bool) (string, string, error) {
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		tlsConfig := &tls.Config{RootCAs: roots}
+		if withClientCert {
+			tlsConfig.Certificates = []tls.Certificate{{Certificate: [][]byte{client.Raw}, PrivateKey: clientKey}}
+		}
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := httpClient.Get(server.URL)
+		if err != nil {
+			return "", "", err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.TLS.PeerCertificates[0].Subject.CommonName, string(body), nil
+	}
+
+	serverName, clientName, err := request(true)
+	require.NoError(t, err)
+	assert.Equal(t, "first", serverName)
+	assert.Equal(t, "client", clientName)
+
+	_, _, err = request(false)
+	assert.Error(t, err, "client certificate is required")
+
+	// Reloaded certificate is used for new connections
+	second, secondKey := createCertificate(t, "second", ca, caKey)
+	writePEM(t, certPath, keyPath, second, secondKey)
+	require.NoError(t, reloader.Reload())
+	serverName, _, err = request(true)
+	require.NoError(t, err)
+	assert.Equal(t, "second", serverName)
+
+	// A failed reload keeps the previous certificate
+	require.NoError(t, os.WriteFile(keyPath, []byte("broken"), 0o600))
+	assert.Error(t, reloader.Reload())
+	serverName, _, err = request(true)
+	require.NoError(t, err)
+	assert.Equal(t, "second", serverName)
+}
+
+func TestTLSHTTP2(t *testing.T) {
+	directory := t.TempDir()
+	certPath, keyPath := filepath.Join(directory, "cert.pem"), filepath.Join(directory, "key.pem")
+	ca, caKey := createCertificate(t, "ca", nil, nil)
+	certificate, key := createCertificate(t, "server", ca, caKey)
+	writePEM(t, certPath, keyPath, certificate, key)
+
+	reloader, err := newTLSReloader(Config{TLS: &TLSConfig{CertFile: certPath, KeyFile: keyPath, MinVersion: TLSVersion12}})
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}), TLSConfig: reloader.TLSConfig()}
+	go server.ServeTLS(listener, "", "")
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}, ForceAttemptHTTP2: true}}
+	resp, err := httpClient.Get("https://" + listener.Addr().String())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+	assert.Equal(t, "h2", resp.TLS.NegotiatedProtocol)
+}
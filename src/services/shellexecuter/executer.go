@@ -7,9 +7,11 @@ import (
 	"log/slog"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/bdoerfchen/webcmd/src/common/execution"
 	"github.com/bdoerfchen/webcmd/src/common/process"
+	"github.com/bdoerfchen/webcmd/src/common/tracing"
 )
 
 type shellExecuter struct {
@@ -22,13 +24,37 @@ func New(size uint, template process.Template) *shellExecuter {
 	}
 }
 
+// Number of shell processes that can be prepared
+func (e *shellExecuter) Capacity() int {
+	return e.pool.Capacity()
+}
+
+// Number of prepared shell processes available to execute
+func (e *shellExecuter) Available() int {
+	return e.pool.Available()
+}
+
+// Change the number of shell processes to prepare, keeping the prepared ones as far as they fit
+func (e *shellExecuter) Resize(size uint) {
+	e.pool.Resize(size)
+}
+
+// Terminate the prepared shell processes and stop preparing new ones
+func (e *shellExecuter) Close(timeout time.Duration) {
+	e.pool.Close(timeout)
+}
+
 func (e *shellExecuter) Execute(ctx context.Context, config execution.Config) (proc *process.Process, exitCode int, err error) {
 	// Get process from pool
+	_, waitSpan := tracing.Start(ctx, "wait for shell")
 	shell, err := e.pool.Take(ctx)
+	waitSpan.End()
 	if err != nil {
 		return nil, 0, fmt.Errorf("taking from pool failed: %w", err)
 	}
 
+	config.ReportStarted(shell.Proc.Process)
+
 	// Env exports prepended to the command
 	var envExportCmd strings.Builder
 	for key, value := range config.Env {
@@ -50,6 +76,7 @@ func (e *shellExecuter) Execute(ctx context.Context, config execution.Config) (p
 	err = shell.Proc.Wait()
 	if err != nil {
 		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr {
+			shell.Signal = process.SignalOf(shell.Proc)
 			return shell, exitErr.ExitCode(), nil
 		}
 
@@ -2,19 +2,25 @@ package shellexecuter
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/bdoerfchen/webcmd/src/common/process"
 )
 
 type shellPool struct {
+	mutex    sync.RWMutex
 	pool     chan *process.Process
+	resized  chan struct{} // Closed when the pool is resized, so waiting takers switch to the new pool
 	template *process.Template
+	closed   bool // Set on close, so no processes are prepared anymore
 }
 
 func NewPool(size uint, template process.Template) *shellPool {
 	// Create pool
 	pool := &shellPool{
 		pool:     make(chan *process.Process, size),
+		resized:  make(chan struct{}),
 		template: &template,
 	}
 
@@ -28,23 +34,64 @@ func NewPool(size uint, template process.Template) *shellPool {
 
 // Number of processes that can be prepared
 func (p *shellPool) Capacity() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
 	return cap(p.pool)
 }
 
 // Number of processes available to take
 func (p *shellPool) Available() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
 	return len(p.pool)
 }
 
 // Take a process from the pool. Returns error if the context is closed before.
 func (p *shellPool) Take(ctx context.Context) (*process.Process, error) {
-	select {
-	case proc := <-p.pool:
-		// add another one back in the background
+	for {
+		p.mutex.RLock()
+		pool, resized := p.pool, p.resized
+		p.mutex.RUnlock()
+
+		select {
+		case proc := <-pool:
+			// add another one back in the background
+			go p.give()
+			return proc, nil
+		case <-resized:
+			continue
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Change the number of processes to prepare. Prepared processes are kept as far as they fit, the rest is terminated
+func (p *shellPool) Resize(size uint) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	previous := cap(p.pool)
+	if int(size) == previous || p.closed {
+		return
+	}
+
+	pool := make(chan *process.Process, size)
+	for len(p.pool) > 0 {
+		proc := <-p.pool
+		select {
+		case pool <- proc:
+		default:
+			discard(proc)
+		}
+	}
+	p.pool = pool
+	close(p.resized)
+	p.resized = make(chan struct{})
+
+	// Processes in use or in preparation are given back to the new pool, so only the difference is added
+	for range int(size) - previous {
 		go p.give()
-		return proc, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
 	}
 }
 
@@ -59,6 +106,57 @@ func (p *shellPool) give() {
 	// Start process
 	proc.Proc.Start()
 
-	// Insert into queue
-	p.pool <- proc
+	// Insert into queue, unless the pool was shrunk or closed
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.closed {
+		discard(proc)
+		return
+	}
+	select {
+	case p.pool <- proc:
+	default:
+		discard(proc)
+	}
+}
+
+// Stop preparing processes and let the prepared ones exit by closing their input. Processes that do not exit within the timeout are killed
+func (p *shellPool) Close(timeout time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+
+	var wait sync.WaitGroup
+	for len(p.pool) > 0 {
+		proc := <-p.pool
+		proc.StdIn.Close()
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			exited := make(chan struct{})
+			go func() {
+				proc.Proc.Wait()
+				close(exited)
+			}()
+			select {
+			case <-exited:
+			case <-time.After(timeout):
+				proc.Proc.Process.Kill()
+				<-exited
+			}
+		}()
+	}
+	wait.Wait()
+}
+
+// Terminate a prepared process that is not needed anymore
+func discard(proc *process.Process) {
+	proc.StdIn.Close()
+	if proc.Proc.Process != nil {
+		proc.Proc.Process.Kill()
+		go proc.Proc.Wait()
+	}
 }
@@ -0,0 +1,59 @@
+package shellexecuter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolResize(t *testing.T) {
+	pool := NewPool(2, process.Template{Command: "cat", OpenStdIn: true})
+	filled := func(size int) func() bool {
+		return func() bool { return pool.Capacity() == size && pool.Available() == size }
+	}
+	require.Eventually(t, filled(2), time.Second, 10*time.Millisecond)
+
+	proc, err := pool.Take(context.Background())
+	require.NoError(t, err)
+	discard(proc)
+
+	pool.Resize(4)
+	assert.Eventually(t, filled(4), time.Second, 10*time.Millisecond)
+
+	pool.Resize(1)
+	assert.Equal(t, 1, pool.Capacity())
+	assert.Eventually(t, filled(1), time.Second, 10*time.Millisecond)
+
+	// A waiting taker is served from the resized pool
+	pool.Resize(0)
+	taken := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		proc, err := pool.Take(ctx)
+		if err == nil {
+			discard(proc)
+		}
+		taken <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	pool.Resize(1)
+	assert.NoError(t, <-taken)
+}
+
+func TestPoolClose(t *testing.T) {
+	pool := NewPool(2, process.Template{Command: "cat", OpenStdIn: true})
+	require.Eventually(t, func() bool { return pool.Available() == 2 }, time.Second, 10*time.Millisecond)
+
+	pool.Close(time.Second)
+	assert.Equal(t, 0, pool.Available())
+
+	// Processes are not prepared anymore after closing
+	pool.Resize(3)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, pool.Available())
+}
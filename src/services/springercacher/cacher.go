@@ -3,10 +3,13 @@ package springercacher
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/bdoerfchen/webcmd/src/common/auth"
 	"github.com/bdoerfchen/webcmd/src/common/config"
 	cache "github.com/victorspringer/http-cache"
 	"github.com/victorspringer/http-cache/adapter/memory"
@@ -14,8 +17,13 @@ import (
 
 // A Cacher implementation that uses the memory cache of Victor Springer internally
 type springerCacher struct {
-	client             *cache.Client
+	adapter            cache.Adapter
+	ttl                time.Duration
 	headerCacheControl string
+	capacity           int
+
+	mutex sync.Mutex
+	keys  map[string]map[uint64]struct{} // Keys of the cached responses by route name
 }
 
 func New(config *config.CacheConfig) (*springerCacher, error) {
@@ -28,8 +36,8 @@ func New(config *config.CacheConfig) (*springerCacher, error) {
 		return nil, fmt.Errorf("error while creating memory adapter: %w", err)
 	}
 
-	// Create client
-	cacheClient, err := cache.NewClient(
+	// Validate client options, clients are created per route
+	_, err = cache.NewClient(
 		cache.ClientWithAdapter(memAdapter),
 		cache.ClientWithTTL(time.Duration(config.TTL)),
 	)
@@ -45,18 +53,103 @@ func New(config *config.CacheConfig) (*springerCacher, error) {
 
 	// Return
 	return &springerCacher{
-		client:             cacheClient,
+		adapter:            memAdapter,
+		ttl:                time.Duration(config.TTL),
 		headerCacheControl: strings.Join(cacheDirectives, ", "),
+		capacity:           int(config.MaxResponsesCached),
+		keys:               make(map[string]map[uint64]struct{}),
 	}, nil
 }
 
-func (c *springerCacher) Cache(handler http.HandlerFunc) http.HandlerFunc {
+func (c *springerCacher) Cache(route string, handler http.HandlerFunc) http.HandlerFunc {
+	// All routes share the memory adapter, the client of a route tracks the keys of its responses.
+	// The options were validated on creation
+	client, _ := cache.NewClient(
+		cache.ClientWithAdapter(&routeAdapter{Adapter: c.adapter, cacher: c, route: route}),
+		cache.ClientWithTTL(c.ttl),
+	)
+
+	// The principal is only part of the key, the handler gets the original URL
+	uncached := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.User != nil {
+			r = r.Clone(r.Context())
+			r.URL.User = nil
+		}
+		handler(w, r)
+	})
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set date explicitly so its not set again by http server. TZ needs to be GMT (RFC1123)
 		w.Header().Add("Date", time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05")+" GMT")
-		// Set cache-control header
-		w.Header().Add("Cache-Control", c.headerCacheControl)
+		// HEAD requests share the cache entry of GET requests. The server omits the body
+		if r.Method == http.MethodHead {
+			r = r.Clone(r.Context())
+			r.Method = http.MethodGet
+		}
+
+		// Responses to authenticated requests are cached per principal and must not be stored by shared caches
+		if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+			w.Header().Add("Cache-Control", "private, "+c.headerCacheControl)
+			r = r.Clone(r.Context())
+			r.URL.User = url.UserPassword(principal.Authenticator, principal.Name)
+		} else {
+			w.Header().Add("Cache-Control", c.headerCacheControl)
+		}
+
 		// Return cache response
-		c.client.Middleware(handler).ServeHTTP(w, r)
+		client.Middleware(uncached).ServeHTTP(w, r)
 	})
 }
+
+func (c *springerCacher) Purge(route string) (purged int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for name, keys := range c.keys {
+		if route != "" && name != route {
+			continue
+		}
+		for key := range keys {
+			// Keys of evicted responses are not counted
+			if _, ok := c.adapter.Get(key); ok {
+				c.adapter.Release(key)
+				purged++
+			}
+		}
+		delete(c.keys, name)
+	}
+
+	return
+}
+
+// Remember the key of a cached response of the route
+func (c *springerCacher) track(route string, key uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.keys[route] == nil {
+		c.keys[route] = make(map[uint64]struct{})
+	}
+	c.keys[route][key] = struct{}{}
+
+	// Forget keys of evicted responses, once there are more keys than responses can be cached
+	if len(c.keys[route]) > 2*c.capacity {
+		for key := range c.keys[route] {
+			if _, ok := c.adapter.Get(key); !ok {
+				delete(c.keys[route], key)
+			}
+		}
+	}
+}
+
+// The shared adapter as seen by the client of a route
+type routeAdapter struct {
+	cache.Adapter
+	cacher *springerCacher
+	route  string
+}
+
+func (a *routeAdapter) Set(key uint64, response []byte, expiration time.Time) {
+	a.Adapter.Set(key, response, expiration)
+	a.cacher.track(a.route, key)
+}
@@ -0,0 +1,104 @@
+package sshexecuter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/bdoerfchen/webcmd/src/common/process"
+	"golang.org/x/crypto/ssh"
+)
+
+// An executer running commands on remote hosts over reused ssh connections
+type sshExecuter struct {
+	pool *connPool
+}
+
+func New() *sshExecuter {
+	return &sshExecuter{
+		pool: newConnPool(),
+	}
+}
+
+// Close the pooled connections, on which running executions fail
+func (e *sshExecuter) Close() {
+	e.pool.Close()
+}
+
+func (e *sshExecuter) Execute(ctx context.Context, config execution.Config) (proc *process.Process, exitCode int, err error) {
+	sshConfig := config.Exec.SSH
+	if sshConfig == nil {
+		return nil, 0, fmt.Errorf("missing ssh config")
+	}
+
+	// Open session on pooled connection. A stale connection is dropped and dialed once more
+	session, err := e.newSession(ctx, config)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer session.Close()
+
+	// Pass env via setenv where the server allows it, export the rest in front of the command
+	var envExportCmd strings.Builder
+	for key, value := range config.Env {
+		if err := session.Setenv(key, value); err != nil {
+			envExportCmd.WriteString(fmt.Sprintf("export %s=%s; ", key, quote(value)))
+		}
+	}
+
+	// Connect streams like for local processes
+	proc = &process.Process{}
+	session.Stdin = config.Stdin
+	session.Stdout, session.Stderr = proc.Writers()
+
+	// Kill remote command when the request is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+	})
+	defer stop()
+
+	// Run and wait for command to finish
+	if err := session.Run(envExportCmd.String() + config.Command); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			proc.Signal = process.NormalizeSignalName(exitErr.Signal())
+			return proc, exitErr.ExitStatus(), nil
+		}
+
+		return nil, 0, fmt.Errorf("error during remote execution: %w", err)
+	}
+
+	return proc, 0, nil
+}
+
+func (e *sshExecuter) Describe() (mode execution.ExecMode, attributes []any) {
+	return execution.ModeSSH, []any{slog.Int("connections", e.pool.Size())}
+}
+
+func (e *sshExecuter) newSession(ctx context.Context, config execution.Config) (*ssh.Session, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := e.pool.Get(ctx, config.Exec.SSH)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to ssh host: %w", err)
+		}
+
+		session, err := client.NewSession()
+		if err == nil {
+			return session, nil
+		}
+
+		e.pool.Drop(config.Exec.SSH, client)
+		if attempt > 0 {
+			return nil, fmt.Errorf("unable to open ssh session: %w", err)
+		}
+	}
+}
+
+// Quote a value for safe use in a POSIX shell
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,222 @@
+package sshexecuter
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/execution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// An in-process ssh server running exec requests with a local bash
+type testServer struct {
+	Address     string
+	KeyFile     string
+	KnownHosts  string
+	RejectEnv   bool
+	Connections atomic.Int32
+}
+
+func startServer(t *testing.T, rejectEnv bool) *testServer {
+	dir := t.TempDir()
+	server := &testServer{RejectEnv: rejectEnv}
+
+	// Host key
+	_, hostPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivate)
+	require.NoError(t, err)
+
+	// Client key written to file
+	clientPublic, clientPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(clientPrivate, "")
+	require.NoError(t, err)
+	server.KeyFile = filepath.Join(dir, "id_ed25519")
+	require.NoError(t, os.WriteFile(server.KeyFile, pem.EncodeToMemory(block), 0600))
+	authorizedKey, err := ssh.NewPublicKey(clientPublic)
+	require.NoError(t, err)
+
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	serverConfig.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	server.Address = listener.Addr().String()
+
+	// Known hosts containing the host key
+	server.KnownHosts = filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(server.Address)}, hostSigner.PublicKey())
+	require.NoError(t, os.WriteFile(server.KnownHosts, []byte(line+"\n"), 0600))
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn, serverConfig)
+		}
+	}()
+
+	return server
+}
+
+func (s *testServer) handle(conn net.Conn, serverConfig *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	s.Connections.Add(1)
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			defer channel.Close()
+			env := []string{}
+			for req := range channelRequests {
+				switch req.Type {
+				case "env":
+					var payload struct{ Name, Value string }
+					ssh.Unmarshal(req.Payload, &payload)
+					if !s.RejectEnv {
+						env = append(env, payload.Name+"="+payload.Value)
+					}
+					req.Reply(!s.RejectEnv, nil)
+				case "exec":
+					var payload struct{ Command string }
+					ssh.Unmarshal(req.Payload, &payload)
+					req.Reply(true, nil)
+
+					cmd := exec.Command("bash", "-c", payload.Command)
+					cmd.Env = env
+					cmd.Stdin = channel
+					cmd.Stdout = channel
+					cmd.Stderr = channel.Stderr()
+					status := uint32(0)
+					if err := cmd.Run(); err != nil {
+						status = uint32(cmd.ProcessState.ExitCode())
+					}
+					channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+					return
+				default:
+					req.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+func (s *testServer) execConfig(command string) execution.Config {
+	route := config.DefaultRoute()
+	route.Exec.SSH = &config.ExecSSH{
+		Host:       s.Address,
+		User:       "webcmd",
+		KeyFile:    s.KeyFile,
+		KnownHosts: s.KnownHosts,
+		Command:    command,
+	}
+
+	return execution.ConfigFromRoute(&route)
+}
+
+func TestExecuteEnv(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		RejectEnv bool
+	}{
+		{Name: "setenv", RejectEnv: false},
+		{Name: "exports", RejectEnv: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			server := startServer(t, tc.RejectEnv)
+			execConfig := server.execConfig(`echo "$WC_NAME"`)
+			execConfig.Env = map[string]string{"WC_NAME": `it's "quoted"; $(id)`}
+
+			proc, exitCode, err := New().Execute(context.Background(), execConfig)
+			require.NoError(t, err)
+
+			assert.Equal(t, 0, exitCode)
+			assert.Equal(t, "it's \"quoted\"; $(id)\n", proc.StdOut.String())
+		})
+	}
+}
+
+func TestExecuteExitCodeAndStreams(t *testing.T) {
+	server := startServer(t, false)
+	execConfig := server.execConfig("cat -; echo error >&2; exit 3")
+	execConfig.Stdin = strings.NewReader("body")
+
+	proc, exitCode, err := New().Execute(context.Background(), execConfig)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "body", proc.StdOut.String())
+	assert.Equal(t, "error\n", proc.StdErr.String())
+}
+
+func TestExecuteReusesConnection(t *testing.T) {
+	server := startServer(t, false)
+	executer := New()
+
+	for range 3 {
+		_, exitCode, err := executer.Execute(context.Background(), server.execConfig("true"))
+		require.NoError(t, err)
+		assert.Equal(t, 0, exitCode)
+	}
+
+	assert.Equal(t, int32(1), server.Connections.Load())
+	assert.Equal(t, 1, executer.pool.Size())
+
+	// Closed on shutdown
+	executer.Close()
+	assert.Equal(t, 0, executer.pool.Size())
+}
+
+func TestExecuteUnknownHostKey(t *testing.T) {
+	server := startServer(t, false)
+	other := startServer(t, false)
+
+	// Use known_hosts of another server
+	execConfig := server.execConfig("true")
+	execConfig.Exec.SSH.KnownHosts = other.KnownHosts
+
+	_, _, err := New().Execute(context.Background(), execConfig)
+	assert.Error(t, err)
+	assert.Equal(t, int32(0), server.Connections.Load())
+}
+
+func TestHostWithPort(t *testing.T) {
+	assert.Equal(t, "example.com:22", hostWithPort("example.com"))
+	assert.Equal(t, "example.com:2222", hostWithPort("example.com:2222"))
+	assert.Equal(t, "[::1]:22", hostWithPort("::1"))
+}
@@ -0,0 +1,167 @@
+package sshexecuter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// A pool of ssh connections that are reused across executions. Connections are identified by their config
+type connPool struct {
+	mutex   sync.Mutex
+	clients map[string]*ssh.Client
+	dialer  net.Dialer
+}
+
+func newConnPool() *connPool {
+	return &connPool{
+		clients: make(map[string]*ssh.Client),
+		dialer:  net.Dialer{Timeout: 10 * time.Second},
+	}
+}
+
+// Number of open connections
+func (p *connPool) Size() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.clients)
+}
+
+// Get an open connection for the config or dial a new one
+func (p *connPool) Get(ctx context.Context, sshConfig *config.ExecSSH) (*ssh.Client, error) {
+	key := poolKey(sshConfig)
+
+	p.mutex.Lock()
+	client, ok := p.clients[key]
+	p.mutex.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	client, err := p.dial(ctx, sshConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if existing, ok := p.clients[key]; ok {
+		// Another request was faster, use its connection instead
+		client.Close()
+		return existing, nil
+	}
+	p.clients[key] = client
+
+	// Remove from pool once the connection is closed
+	go func() {
+		client.Wait()
+		p.Drop(sshConfig, client)
+	}()
+
+	return client, nil
+}
+
+// Remove a connection from the pool and close it
+func (p *connPool) Drop(sshConfig *config.ExecSSH, client *ssh.Client) {
+	key := poolKey(sshConfig)
+
+	p.mutex.Lock()
+	if p.clients[key] == client {
+		delete(p.clients, key)
+	}
+	p.mutex.Unlock()
+
+	client.Close()
+}
+
+// Close all connections
+func (p *connPool) Close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for key, client := range p.clients {
+		client.Close()
+		delete(p.clients, key)
+	}
+}
+
+func (p *connPool) dial(ctx context.Context, sshConfig *config.ExecSSH) (*ssh.Client, error) {
+	clientConfig, err := clientConfigFor(sshConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	address := hostWithPort(sshConfig.Host)
+	conn, err := p.dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", address, err)
+	}
+
+	// Abort handshake when the request is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", address, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, channels, requests), nil
+}
+
+// Build the client config with key authentication and host key verification
+func clientConfigFor(sshConfig *config.ExecSSH) (*ssh.ClientConfig, error) {
+	key, err := os.ReadFile(sshConfig.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read key file: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse key file: %w", err)
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !sshConfig.InsecureIgnoreHostKey {
+		path := sshConfig.KnownHosts
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("unable to find default known_hosts file: %w", err)
+			}
+			path = filepath.Join(home, ".ssh", "known_hosts")
+		}
+
+		hostKeyCallback, err = knownhosts.New(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load known_hosts file: %w", err)
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User:            sshConfig.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+	}, nil
+}
+
+func poolKey(sshConfig *config.ExecSSH) string {
+	return fmt.Sprintf("%s@%s|%s|%s|%v", sshConfig.User, hostWithPort(sshConfig.Host), sshConfig.KeyFile, sshConfig.KnownHosts, sshConfig.InsecureIgnoreHostKey)
+}
+
+// Add the default ssh port if the host has none
+func hostWithPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+
+	return net.JoinHostPort(host, "22")
+}
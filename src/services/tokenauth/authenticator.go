@@ -0,0 +1,90 @@
+package tokenauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+)
+
+// An authenticator accepting static bearer tokens
+type tokenAuthenticator struct {
+	tokens    []namedToken
+	challenge string
+}
+
+type namedToken struct {
+	name string
+	hash [sha256.Size]byte // Tokens are compared by their hashes, so the comparison takes the same time for every token length
+}
+
+func New(config *config.AuthBearer) (*tokenAuthenticator, error) {
+	var tokens []namedToken
+	if config.TokensFile != "" {
+		content, err := os.ReadFile(config.TokensFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tokens file: %w", err)
+		}
+		fileTokens, err := parseTokens(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tokens file %s: %w", config.TokensFile, err)
+		}
+		tokens = append(tokens, fileTokens...)
+	}
+	if config.TokensEnv != "" {
+		envTokens, err := parseTokens(strings.ReplaceAll(os.Getenv(config.TokensEnv), ",", "\n"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tokens in env variable %s: %w", config.TokensEnv, err)
+		}
+		tokens = append(tokens, envTokens...)
+	}
+
+	return &tokenAuthenticator{
+		tokens:    tokens,
+		challenge: fmt.Sprintf(`Bearer realm="%s"`, config.Realm),
+	}, nil
+}
+
+// Read "name:token" pairs, one per line
+func parseTokens(content string) ([]namedToken, error) {
+	var tokens []namedToken
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, token, ok := strings.Cut(line, ":")
+		if !ok || name == "" || token == "" {
+			return nil, fmt.Errorf("entry %v is not a 'name:token' pair", i+1)
+		}
+		tokens = append(tokens, namedToken{name: name, hash: sha256.Sum256([]byte(token))})
+	}
+
+	return tokens, nil
+}
+
+func (a *tokenAuthenticator) Authenticate(req *http.Request) (*auth.Principal, error) {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, auth.ErrNoCredentials
+	}
+
+	hash := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	for _, known := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], known.hash[:]) == 1 {
+			return &auth.Principal{Name: known.name}, nil
+		}
+	}
+
+	return nil, auth.ErrInvalidCredentials
+}
+
+func (a *tokenAuthenticator) Challenge() string {
+	return a.challenge
+}
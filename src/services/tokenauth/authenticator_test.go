@@ -0,0 +1,51 @@
+package tokenauth
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bdoerfchen/webcmd/src/common/auth"
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("# tokens\nci:file-token\n"), 0o600))
+	t.Setenv("TEST_TOKENS", "deploy:env-token,monitor:other-token")
+
+	authenticator, err := New(&config.AuthBearer{TokensFile: path, TokensEnv: "TEST_TOKENS", Realm: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, `Bearer realm="test"`, authenticator.Challenge())
+
+	testCases := []struct {
+		Name          string
+		Authorization string
+		ExpectedName  string
+		ExpectedError error
+	}{
+		{Name: "file token", Authorization: "Bearer file-token", ExpectedName: "ci"},
+		{Name: "env token", Authorization: "bearer env-token", ExpectedName: "deploy"},
+		{Name: "unknown token", Authorization: "Bearer file", ExpectedError: auth.ErrInvalidCredentials},
+		{Name: "other scheme", Authorization: "Basic YWxpY2U6c2VjcmV0", ExpectedError: auth.ErrNoCredentials},
+		{Name: "no credentials", ExpectedError: auth.ErrNoCredentials},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", tc.Authorization)
+
+			principal, err := authenticator.Authenticate(req)
+			if tc.ExpectedError != nil {
+				assert.ErrorIs(t, err, tc.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedName, principal.Name)
+		})
+	}
+}
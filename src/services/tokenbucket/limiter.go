@@ -0,0 +1,120 @@
+package tokenbucket
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/ratelimit"
+)
+
+// Upper bound of tracked keys. When reached, the least recently used bucket is evicted for a new key
+const maxKeys = 100_000
+
+// Idle buckets are evicted in this interval
+const cleanupInterval = time.Minute
+
+// A rate limiter with a token bucket per key
+type tokenBucketLimiter struct {
+	rate     float64 // Tokens per second
+	capacity float64
+	now      func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[string]*list.Element // Elements of recent, holding the buckets by key
+	recent  *list.List               // Buckets ordered by their last use, most recent first
+	maxKeys int
+	stop    chan struct{}
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time // Time of the last refill
+}
+
+func New(limit *config.RateLimit) *tokenBucketLimiter {
+	limiter := &tokenBucketLimiter{
+		rate:     float64(limit.Requests) / time.Duration(limit.Period).Seconds(),
+		capacity: float64(limit.Burst),
+		now:      time.Now,
+		buckets:  make(map[string]*list.Element),
+		recent:   list.New(),
+		maxKeys:  maxKeys,
+		stop:     make(chan struct{}),
+	}
+
+	// Evict idle buckets in the background, so memory stays bounded
+	go func() {
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-limiter.stop:
+				return
+			case <-ticker.C:
+				limiter.mutex.Lock()
+				limiter.evictIdle()
+				limiter.mutex.Unlock()
+			}
+		}
+	}()
+
+	return limiter
+}
+
+func (l *tokenBucketLimiter) Allow(key string) ratelimit.Result {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	var b *bucket
+	if element, ok := l.buckets[key]; ok {
+		l.recent.MoveToFront(element)
+		b = element.Value.(*bucket)
+	} else {
+		// Forgetting the least recently used key only resets its limit, rejecting new keys would lock out new clients
+		if len(l.buckets) >= l.maxKeys {
+			delete(l.buckets, l.recent.Remove(l.recent.Back()).(*bucket).key)
+		}
+		b = &bucket{key: key, tokens: l.capacity, last: now}
+		l.buckets[key] = l.recent.PushFront(b)
+	}
+
+	// Refill since last request
+	b.tokens = min(l.capacity, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	result := ratelimit.Result{Limit: int(l.capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - b.tokens)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = l.duration(l.capacity - b.tokens)
+
+	return result
+}
+
+// Time needed to refill the tokens
+func (l *tokenBucketLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// Remove the buckets unused for long enough to be full by now, starting with the least recently used. Requires the mutex to be locked
+func (l *tokenBucketLimiter) evictIdle() {
+	refillTime := l.duration(l.capacity)
+	now := l.now()
+	for element := l.recent.Back(); element != nil && now.Sub(element.Value.(*bucket).last) >= refillTime; element = l.recent.Back() {
+		delete(l.buckets, l.recent.Remove(element).(*bucket).key)
+	}
+}
+
+// Stop the background eviction
+func (l *tokenBucketLimiter) Stop() {
+	close(l.stop)
+}
@@ -0,0 +1,68 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/timem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllow(t *testing.T) {
+	limiter := New(&config.RateLimit{Requests: 2, Period: timem.Duration(2 * time.Second), Burst: 3})
+	defer limiter.Stop()
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	testCases := []struct {
+		Name              string
+		Key               string
+		Elapsed           time.Duration // Time passed since the previous request
+		ExpectedAllowed   bool
+		ExpectedRemaining int
+	}{
+		{Name: "first request", Key: "a", ExpectedAllowed: true, ExpectedRemaining: 2},
+		{Name: "second request", Key: "a", ExpectedAllowed: true, ExpectedRemaining: 1},
+		{Name: "burst exhausted", Key: "a", ExpectedAllowed: true, ExpectedRemaining: 0},
+		{Name: "limited", Key: "a", ExpectedAllowed: false, ExpectedRemaining: 0},
+		{Name: "other key", Key: "b", ExpectedAllowed: true, ExpectedRemaining: 2},
+		{Name: "refilled", Key: "a", Elapsed: time.Second, ExpectedAllowed: true, ExpectedRemaining: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			now = now.Add(tc.Elapsed)
+			result := limiter.Allow(tc.Key)
+
+			assert.Equal(t, tc.ExpectedAllowed, result.Allowed)
+			assert.Equal(t, tc.ExpectedRemaining, result.Remaining)
+			assert.Equal(t, 3, result.Limit)
+			if !result.Allowed {
+				assert.Equal(t, time.Second, result.RetryAfter)
+			}
+		})
+	}
+
+	// Idle buckets are evicted once they are full again
+	now = now.Add(time.Minute)
+	limiter.evictIdle()
+	assert.Empty(t, limiter.buckets)
+	assert.Zero(t, limiter.recent.Len())
+}
+
+func TestAllowEvictsLeastRecentlyUsed(t *testing.T) {
+	limiter := New(&config.RateLimit{Requests: 1, Period: timem.Duration(time.Hour), Burst: 1})
+	defer limiter.Stop()
+	limiter.maxKeys = 2
+
+	assert.True(t, limiter.Allow("a").Allowed)
+	assert.True(t, limiter.Allow("b").Allowed)
+	assert.False(t, limiter.Allow("a").Allowed)
+
+	// New keys are allowed when the limit of keys is reached, replacing the least recently used one
+	assert.True(t, limiter.Allow("c").Allowed)
+	assert.Len(t, limiter.buckets, 2)
+	assert.NotContains(t, limiter.buckets, "b")
+	assert.False(t, limiter.Allow("a").Allowed)
+}
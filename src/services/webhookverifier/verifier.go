@@ -0,0 +1,222 @@
+package webhookverifier
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/webhook"
+)
+
+// Time a delivery ID is remembered to detect replays. Deliveries with signed timestamps are remembered as long as their timestamp is accepted
+const replayWindow = 24 * time.Hour
+
+// Upper bound of remembered delivery IDs. The oldest is forgotten when reached
+const maxDeliveries = 100_000
+
+// Verifies the signature, or the token for GitLab, of webhook deliveries with a shared secret
+type webhookVerifier struct {
+	config *config.RouteWebhook
+	secret []byte
+	hash   func() hash.Hash
+	now    func() time.Time
+
+	mutex      sync.Mutex
+	deliveries map[string]*list.Element // Elements of received, holding the deliveries by ID
+	received   *list.List               // Remembered deliveries ordered by the time they expire, oldest first
+}
+
+type delivery struct {
+	id      string
+	expires time.Time
+}
+
+func New(webhookConfig *config.RouteWebhook) (*webhookVerifier, error) {
+	var secret string
+	if webhookConfig.SecretFile != "" {
+		content, err := os.ReadFile(webhookConfig.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read webhook secret file: %w", err)
+		}
+		secret = strings.TrimSpace(string(content))
+	} else {
+		secret = os.Getenv(webhookConfig.SecretEnv)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("webhook secret is empty")
+	}
+
+	verifier := &webhookVerifier{
+		config:     webhookConfig,
+		secret:     []byte(secret),
+		hash:       sha256.New,
+		now:        time.Now,
+		deliveries: make(map[string]*list.Element),
+		received:   list.New(),
+	}
+	if webhookConfig.Provider == config.WebhookGeneric {
+		switch webhookConfig.Algorithm {
+		case "sha1":
+			verifier.hash = sha1.New
+		case "sha512":
+			verifier.hash = sha512.New
+		}
+	}
+
+	return verifier, nil
+}
+
+func (v *webhookVerifier) Verify(header http.Header, body []byte) error {
+	var err error
+	switch {
+	case v.config.Provider == config.WebhookGitHub:
+		err = v.verifySignature(header.Get("X-Hub-Signature-256"), "sha256=", body)
+	case v.config.Provider == config.WebhookGitLab:
+		err = v.verifyToken(header.Get("X-Gitlab-Token"))
+	case v.config.SignsTimestamp():
+		err = v.verifyTimestamp(header.Get(v.config.TimestampHeader))
+		if err == nil {
+			err = v.verifySignature(header.Get(v.config.Header), v.config.Prefix, slices.Concat([]byte(header.Get(v.config.TimestampHeader)+"."), body))
+		}
+	default:
+		err = v.verifySignature(header.Get(v.config.Header), v.config.Prefix, body)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Deliveries are only remembered once verified, so they can not be blocked by unsigned requests
+	if !v.config.RejectReplays {
+		return nil
+	}
+	if v.config.SignsTimestamp() {
+		// The signature covers the timestamp, so it identifies the delivery and can not be changed by a replay.
+		// It has to be remembered until the timestamp is outside the tolerance in both directions
+		return v.remember(header.Get(v.config.Header), 2*time.Duration(v.config.Tolerance))
+	}
+	return v.remember(header.Get(v.config.DeliveryHeader), replayWindow)
+}
+
+func (v *webhookVerifier) Inherit(previous webhook.Verifier) {
+	other, ok := previous.(*webhookVerifier)
+	if !ok || other == v {
+		return
+	}
+
+	other.mutex.Lock()
+	var deliveries []*delivery
+	for element := other.received.Front(); element != nil; element = element.Next() {
+		deliveries = append(deliveries, element.Value.(*delivery))
+	}
+	other.mutex.Unlock()
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for element := v.received.Front(); element != nil; element = element.Next() {
+		deliveries = append(deliveries, element.Value.(*delivery))
+	}
+	slices.SortStableFunc(deliveries, func(a, b *delivery) int { return a.expires.Compare(b.expires) })
+
+	v.deliveries = make(map[string]*list.Element, len(deliveries))
+	v.received = list.New()
+	for _, d := range deliveries {
+		if previous, ok := v.deliveries[d.id]; ok {
+			v.received.Remove(previous)
+		}
+		v.deliveries[d.id] = v.received.PushBack(d)
+	}
+}
+
+// Compare the hex encoded HMAC of the body
+func (v *webhookVerifier) verifySignature(value, prefix string, body []byte) error {
+	if value == "" {
+		return webhook.ErrMissingSignature
+	}
+	encoded, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return webhook.ErrInvalidSignature
+	}
+	signature, err := hex.DecodeString(encoded)
+	if err != nil {
+		return webhook.ErrInvalidSignature
+	}
+
+	mac := hmac.New(v.hash, v.secret)
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return webhook.ErrInvalidSignature
+	}
+	return nil
+}
+
+// Compare the token with the secret. Both are hashed, so the comparison takes the same time for every token length
+func (v *webhookVerifier) verifyToken(token string) error {
+	if token == "" {
+		return webhook.ErrMissingSignature
+	}
+	tokenHash, secretHash := sha256.Sum256([]byte(token)), sha256.Sum256(v.secret)
+	if subtle.ConstantTimeCompare(tokenHash[:], secretHash[:]) != 1 {
+		return webhook.ErrInvalidSignature
+	}
+	return nil
+}
+
+// Check that the signed timestamp is within the tolerance
+func (v *webhookVerifier) verifyTimestamp(value string) error {
+	if value == "" {
+		return webhook.ErrMissingSignature
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return webhook.ErrInvalidSignature
+	}
+	if difference := v.now().Sub(time.Unix(seconds, 0)).Abs(); difference > time.Duration(v.config.Tolerance) {
+		return webhook.ErrExpired
+	}
+	return nil
+}
+
+// Remember the delivery ID for the window, or return an error if it was already received
+func (v *webhookVerifier) remember(id string, window time.Duration) error {
+	if id == "" {
+		return webhook.ErrMissingDelivery
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	// All deliveries of the verifier are remembered for the same window, so the oldest expires first
+	now := v.now()
+	for element := v.received.Front(); element != nil && !now.Before(element.Value.(*delivery).expires); element = v.received.Front() {
+		v.forget(element)
+	}
+
+	if _, ok := v.deliveries[id]; ok {
+		return webhook.ErrReplayed
+	}
+	if len(v.deliveries) >= maxDeliveries {
+		v.forget(v.received.Front())
+	}
+	v.deliveries[id] = v.received.PushBack(&delivery{id: id, expires: now.Add(window)})
+
+	return nil
+}
+
+// Remove a remembered delivery. Requires the mutex to be locked
+func (v *webhookVerifier) forget(element *list.Element) {
+	delete(v.deliveries, v.received.Remove(element).(*delivery).id)
+}
@@ -0,0 +1,154 @@
+package webhookverifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bdoerfchen/webcmd/src/common/config"
+	"github.com/bdoerfchen/webcmd/src/common/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sign(hash func() hash.Hash, secret, body string) string {
+	mac := hmac.New(hash, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerify(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_SECRET", "secret")
+	body := `{"ref":"refs/heads/main"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	later := strconv.FormatInt(time.Now().Unix()+1, 10)
+	old := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	testCases := []struct {
+		Name          string
+		Config        config.RouteWebhook
+		Headers       []map[string]string // Headers of consecutive deliveries
+		ExpectedError []error
+	}{
+		{
+			Name:          "github",
+			Config:        config.RouteWebhook{Provider: config.WebhookGitHub},
+			Headers:       []map[string]string{{"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "secret", body)}},
+			ExpectedError: []error{nil},
+		},
+		{
+			Name:   "github invalid signature",
+			Config: config.RouteWebhook{Provider: config.WebhookGitHub},
+			Headers: []map[string]string{
+				{"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "other", body)},
+				{"X-Hub-Signature-256": sign(sha256.New, "secret", body)},
+				{},
+			},
+			ExpectedError: []error{webhook.ErrInvalidSignature, webhook.ErrInvalidSignature, webhook.ErrMissingSignature},
+		},
+		{
+			Name:          "gitlab",
+			Config:        config.RouteWebhook{Provider: config.WebhookGitLab},
+			Headers:       []map[string]string{{"X-Gitlab-Token": "secret"}, {"X-Gitlab-Token": "secret2"}},
+			ExpectedError: []error{nil, webhook.ErrInvalidSignature},
+		},
+		{
+			Name:          "generic",
+			Config:        config.RouteWebhook{Header: "X-Signature", Algorithm: "sha1", Prefix: "sha1="},
+			Headers:       []map[string]string{{"X-Signature": "sha1=" + sign(sha1.New, "secret", body)}, {"X-Signature": "sha1=" + sign(sha256.New, "secret", body)}},
+			ExpectedError: []error{nil, webhook.ErrInvalidSignature},
+		},
+		{
+			Name:   "replays",
+			Config: config.RouteWebhook{Provider: config.WebhookGitLab, RejectReplays: true},
+			Headers: []map[string]string{
+				{"X-Gitlab-Token": "secret", "X-Gitlab-Event-UUID": "1"},
+				{"X-Gitlab-Token": "wrong", "X-Gitlab-Event-UUID": "2"},
+				{"X-Gitlab-Token": "secret", "X-Gitlab-Event-UUID": "2"},
+				{"X-Gitlab-Token": "secret", "X-Gitlab-Event-UUID": "1"},
+				{"X-Gitlab-Token": "secret"},
+			},
+			ExpectedError: []error{nil, webhook.ErrInvalidSignature, nil, webhook.ErrReplayed, webhook.ErrMissingDelivery},
+		},
+		{
+			Name:   "signed timestamp",
+			Config: config.RouteWebhook{Header: "X-Signature", Prefix: "v1=", TimestampHeader: "X-Timestamp", RejectReplays: true},
+			Headers: []map[string]string{
+				{"X-Timestamp": now, "X-Signature": "v1=" + sign(sha256.New, "secret", now+"."+body)},
+				{"X-Timestamp": now, "X-Signature": "v1=" + sign(sha256.New, "secret", now+"."+body)},
+				{"X-Timestamp": later, "X-Signature": "v1=" + sign(sha256.New, "secret", now+"."+body)},
+				{"X-Timestamp": now, "X-Signature": "v1=" + sign(sha256.New, "secret", body)},
+				{"X-Timestamp": old, "X-Signature": "v1=" + sign(sha256.New, "secret", old+"."+body)},
+				{"X-Signature": "v1=" + sign(sha256.New, "secret", body)},
+			},
+			ExpectedError: []error{nil, webhook.ErrReplayed, webhook.ErrInvalidSignature, webhook.ErrInvalidSignature, webhook.ErrExpired, webhook.ErrMissingSignature},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Config.SecretEnv = "TEST_WEBHOOK_SECRET"
+			tc.Config.Check()
+			verifier, err := New(&tc.Config)
+			require.NoError(t, err)
+
+			for i, headers := range tc.Headers {
+				header := make(http.Header)
+				for name, value := range headers {
+					header.Set(name, value)
+				}
+				err := verifier.Verify(header, []byte(body))
+				if tc.ExpectedError[i] == nil {
+					assert.NoError(t, err, "delivery %v", i+1)
+				} else {
+					assert.ErrorIs(t, err, tc.ExpectedError[i], "delivery %v", i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestInherit(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_SECRET", "secret")
+	webhookConfig := config.RouteWebhook{Provider: config.WebhookGitLab, RejectReplays: true, SecretEnv: "TEST_WEBHOOK_SECRET"}
+	webhookConfig.Check()
+	header := http.Header{"X-Gitlab-Token": {"secret"}, "X-Gitlab-Event-Uuid": {"1"}}
+
+	previous, err := New(&webhookConfig)
+	require.NoError(t, err)
+	require.NoError(t, previous.Verify(header, nil))
+
+	// Deliveries received before a reload are still rejected as replays
+	verifier, err := New(&webhookConfig)
+	require.NoError(t, err)
+	verifier.Inherit(previous)
+	assert.ErrorIs(t, verifier.Verify(header, nil), webhook.ErrReplayed)
+}
+
+func TestRememberExpiry(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_SECRET", "secret")
+	webhookConfig := config.RouteWebhook{Provider: config.WebhookGitLab, RejectReplays: true, SecretEnv: "TEST_WEBHOOK_SECRET"}
+	webhookConfig.Check()
+	verifier, err := New(&webhookConfig)
+	require.NoError(t, err)
+	now := time.Now()
+	verifier.now = func() time.Time { return now }
+
+	require.NoError(t, verifier.remember("1", time.Hour))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, verifier.remember("2", time.Hour))
+	assert.ErrorIs(t, verifier.remember("1", time.Hour), webhook.ErrReplayed)
+
+	// Expired deliveries are forgotten, starting with the oldest
+	now = now.Add(45 * time.Minute)
+	assert.NoError(t, verifier.remember("3", time.Hour))
+	assert.NotContains(t, verifier.deliveries, "1")
+	assert.ErrorIs(t, verifier.remember("2", time.Hour), webhook.ErrReplayed)
+	assert.Equal(t, 2, verifier.received.Len())
+}